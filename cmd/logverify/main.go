@@ -0,0 +1,44 @@
+// Command logverify walks a JSON lines log written through extn.NewHashChainLogger and
+// reports the first record whose hash chain link is broken.
+//
+//	LOG_CHAIN_KEY=<hex key> logverify app.log
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/achuala/kratos-extn/pkg/crypto"
+)
+
+func main() {
+	keyHex := flag.String("key", os.Getenv("LOG_CHAIN_KEY"), "hex encoded HMAC key, defaults to $LOG_CHAIN_KEY")
+	flag.Parse()
+	if flag.NArg() != 1 || *keyHex == "" {
+		fmt.Fprintln(os.Stderr, "usage: logverify [-key hex] <file>")
+		os.Exit(2)
+	}
+	key, err := hex.DecodeString(*keyHex)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid key:", err)
+		os.Exit(2)
+	}
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer f.Close()
+	broken, err := crypto.VerifyChain(f, key, crypto.DecodeJSONRecord)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if broken != nil {
+		fmt.Println(broken.Error())
+		os.Exit(1)
+	}
+	fmt.Println("hash chain intact")
+}
@@ -0,0 +1,150 @@
+package extn
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OverflowPolicy decides what AsyncLogger does with a record when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the queue, applying backpressure on the caller.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop discards the record and counts it as dropped.
+	OverflowDrop
+)
+
+// AsyncOption is an AsyncLogger option.
+type AsyncOption func(*AsyncLogger)
+
+// WithAsyncQueueSize sets the number of records buffered before the overflow policy applies.
+func WithAsyncQueueSize(size int) AsyncOption {
+	return func(l *AsyncLogger) {
+		l.size = size
+	}
+}
+
+// WithOverflowPolicy sets the overflow policy, OverflowBlock by default.
+func WithOverflowPolicy(policy OverflowPolicy) AsyncOption {
+	return func(l *AsyncLogger) {
+		l.policy = policy
+	}
+}
+
+// WithDroppedCounter sets a counter incremented for every dropped record.
+func WithDroppedCounter(counter metric.Int64Counter) AsyncOption {
+	return func(l *AsyncLogger) {
+		l.counter = counter
+	}
+}
+
+type asyncRecord struct {
+	level   log.Level
+	keyvals []interface{}
+}
+
+// AsyncLogger is a log.Logger writing records to the wrapped logger from a background
+// goroutine through a bounded queue. It implements transport.Server so it can be passed to
+// kratos.Server and get flushed when the app stops, or Stop can be called directly.
+type AsyncLogger struct {
+	logger  log.Logger
+	size    int
+	policy  OverflowPolicy
+	counter metric.Int64Counter
+	dropped atomic.Uint64
+
+	mu      sync.RWMutex
+	closed  bool
+	senders sync.WaitGroup
+	queue   chan asyncRecord
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+// NewAsyncLogger starts an AsyncLogger writing to logger.
+func NewAsyncLogger(logger log.Logger, opts ...AsyncOption) *AsyncLogger {
+	l := &AsyncLogger{logger: logger, size: 1024}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.queue = make(chan asyncRecord, l.size)
+	l.stopped = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.run()
+	return l
+}
+
+func (l *AsyncLogger) run() {
+	defer close(l.done)
+	for r := range l.queue {
+		_ = l.logger.Log(r.level, r.keyvals...)
+	}
+}
+
+func (l *AsyncLogger) Log(level log.Level, keyvals ...interface{}) error {
+	l.mu.RLock()
+	if l.closed {
+		l.mu.RUnlock()
+		// write through once stopped so late records are not lost
+		return l.logger.Log(level, keyvals...)
+	}
+	// the lock is not held while sending so Stop never waits on a full queue
+	l.senders.Add(1)
+	l.mu.RUnlock()
+	defer l.senders.Done()
+	r := asyncRecord{level: level, keyvals: append([]interface{}(nil), keyvals...)}
+	if l.policy == OverflowBlock {
+		select {
+		case l.queue <- r:
+			return nil
+		case <-l.stopped:
+			return l.logger.Log(level, keyvals...)
+		}
+	}
+	select {
+	case l.queue <- r:
+	default:
+		l.dropped.Add(1)
+		if l.counter != nil {
+			l.counter.Add(context.Background(), 1)
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of records discarded because the queue was full.
+func (l *AsyncLogger) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// Start implements transport.Server, the logger is already running.
+func (l *AsyncLogger) Start(context.Context) error {
+	return nil
+}
+
+// Stop flushes the queued records, waiting until done or ctx is cancelled.
+// Records logged afterwards are written synchronously.
+func (l *AsyncLogger) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.stopped)
+		go func() {
+			// close the queue once no Log call can send to it anymore
+			l.senders.Wait()
+			close(l.queue)
+		}()
+	}
+	l.mu.Unlock()
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
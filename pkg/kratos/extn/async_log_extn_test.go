@@ -0,0 +1,73 @@
+package extn
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type blockingLogger struct {
+	mu      sync.Mutex
+	release chan struct{}
+	records int
+}
+
+func (l *blockingLogger) Log(log.Level, ...interface{}) error {
+	<-l.release
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records++
+	return nil
+}
+
+func TestAsyncLoggerDrop(t *testing.T) {
+	sink := &blockingLogger{release: make(chan struct{})}
+	logger := NewAsyncLogger(sink, WithAsyncQueueSize(2), WithOverflowPolicy(OverflowDrop))
+	for i := 0; i < 10; i++ {
+		_ = logger.Log(log.LevelInfo, "n", i)
+	}
+	// at most one record in flight in the worker and two queued
+	if dropped := logger.Dropped(); dropped < 7 {
+		t.Fatalf("expected at least 7 dropped records, got %d", dropped)
+	}
+	close(sink.release)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := logger.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if written := uint64(sink.records) + logger.Dropped(); written != 10 {
+		t.Fatalf("expected every record written or dropped, got %d", written)
+	}
+	_ = logger.Log(log.LevelInfo, "n", "late")
+	if sink.records+int(logger.Dropped()) != 11 {
+		t.Fatal("expected records after stop to be written synchronously")
+	}
+}
+
+func TestAsyncLoggerStopDeadline(t *testing.T) {
+	sink := &blockingLogger{release: make(chan struct{})}
+	defer close(sink.release)
+	l := NewAsyncLogger(sink, WithAsyncQueueSize(1))
+	for i := 0; i < 3; i++ {
+		// the first record is taken by the stuck sink, the second fills the queue, the third blocks
+		go func() { _ = l.Log(log.LevelInfo, "msg", "blocked") }()
+	}
+	time.Sleep(time.Millisecond * 20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- l.Stop(ctx) }()
+	select {
+	case err := <-stopped:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("expected deadline error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Stop to honour its deadline while Log is blocked")
+	}
+}
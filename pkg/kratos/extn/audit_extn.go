@@ -0,0 +1,285 @@
+package extn
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/achuala/kratos-extn/api/gen"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is a single entry of the audit trail.
+type AuditEvent struct {
+	Time          time.Time `json:"time"`
+	Actor         string    `json:"actor"`
+	Operation     string    `json:"operation"`
+	Resource      string    `json:"resource,omitempty"`
+	Outcome       string    `json:"outcome"`
+	Code          int32     `json:"code"`
+	Reason        string    `json:"reason,omitempty"`
+	CorrelationId string    `json:"correlationId,omitempty"`
+	Request       string    `json:"request,omitempty"`
+}
+
+// AuditSink receives the audit events emitted by the Audit middleware.
+type AuditSink interface {
+	Write(ctx context.Context, event *AuditEvent) error
+}
+
+// AuditOption is an Audit middleware option.
+type AuditOption func(*auditOptions)
+
+type auditOptions struct {
+	actor   func(ctx context.Context) string
+	onError func(ctx context.Context, event *AuditEvent, err error)
+}
+
+// WithAuditActor sets the function resolving the actor of a request not set with
+// SetAuditActor. By default it is the RequestIdentity of the signature scheme, or else a
+// fingerprint of the Authorization credential, never its unverified claims.
+func WithAuditActor(actor func(ctx context.Context) string) AuditOption {
+	return func(o *auditOptions) {
+		o.actor = actor
+	}
+}
+
+// WithAuditErrorHandler sets the function called when the sink fails to record an event.
+func WithAuditErrorHandler(onError func(ctx context.Context, event *AuditEvent, err error)) AuditOption {
+	return func(o *auditOptions) {
+		o.onError = onError
+	}
+}
+
+// auditActorKey holds the actor verified by the middleware running inside Audit
+type auditActorKey struct{}
+
+// SetAuditActor records the actor verified by an authentication middleware or handler
+// running inside Audit, it takes precedence over the WithAuditActor function.
+func SetAuditActor(ctx context.Context, actor string) {
+	if verified, ok := ctx.Value(auditActorKey{}).(*string); ok {
+		*verified = actor
+	}
+}
+
+// Audit is a server middleware recording an audit event for every method annotated with
+// the `auditable` method option, including calls rejected by the middleware it wraps. Sink
+// failures never fail the request.
+func Audit(sink AuditSink, opts ...AuditOption) middleware.Middleware {
+	o := &auditOptions{
+		actor:   actorFromServerContext,
+		onError: func(context.Context, *AuditEvent, error) {},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			info, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			auditable := auditableFromOperation(info.Operation())
+			if !auditable.GetAudit() {
+				return handler(ctx, req)
+			}
+			verified := new(string)
+			reply, err = handler(context.WithValue(ctx, auditActorKey{}, verified), req)
+			actor := *verified
+			if actor == "" {
+				actor = o.actor(ctx)
+			}
+			event := &AuditEvent{
+				Time:          time.Now().UTC(),
+				Actor:         actor,
+				Operation:     info.Operation(),
+				Outcome:       AuditOutcomeSuccess,
+				CorrelationId: getCorrelationIdFromCtx(ctx),
+				Request:       extractArgs(req),
+			}
+			if msg, ok := req.(proto.Message); ok && auditable.GetResource() != "" {
+				event.Resource = resolveFieldPath(msg.ProtoReflect(), auditable.GetResource())
+			}
+			if se := errors.FromError(err); se != nil {
+				event.Outcome = AuditOutcomeFailure
+				event.Code = se.Code
+				event.Reason = se.Reason
+			}
+			if werr := sink.Write(ctx, event); werr != nil {
+				o.onError(ctx, event, werr)
+			}
+			return
+		}
+	}
+}
+
+// auditableFromOperation returns the `auditable` option of the method identified by
+// a kratos operation such as /package.Service/Method
+func auditableFromOperation(operation string) *pb.Auditable {
+	name := strings.ReplaceAll(strings.TrimPrefix(operation, "/"), "/", ".")
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil
+	}
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok || method.Options() == nil {
+		return nil
+	}
+	auditable, _ := proto.GetExtension(method.Options(), pb.E_Auditable).(*pb.Auditable)
+	return auditable
+}
+
+// resolveFieldPath returns the string value of the dot separated field path in m
+func resolveFieldPath(m protoreflect.Message, path string) string {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(part))
+		if fd == nil || !m.Has(fd) {
+			return ""
+		}
+		if i == len(parts)-1 {
+			return m.Get(fd).String()
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return ""
+		}
+		m = m.Get(fd).Message()
+	}
+	return ""
+}
+
+// actorFromServerContext returns the user, or else the calling system, of the signed identity
+// headers, or a fingerprint of the credential
+func actorFromServerContext(ctx context.Context) string {
+	if identity, ok := IdentityFromContext(ctx); ok {
+		if identity.UserId != "" {
+			return identity.UserId
+		}
+		if identity.SystemPeer != "" {
+			return identity.SystemPeer
+		}
+	}
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		if authorization := tr.RequestHeader().Get(string(CtxAuthorizationKey)); authorization != "" {
+			return credentialFingerprint(authorization)
+		}
+	}
+	return "anonymous"
+}
+
+// credentialFingerprint identifies the credential of an Authorization header value without
+// exposing it
+func credentialFingerprint(authorization string) string {
+	scheme, credential, found := strings.Cut(authorization, " ")
+	if !found {
+		credential = scheme
+	}
+	sum := sha256.Sum256([]byte(credential))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
+
+// ActorFromAuthorization derives a display name from an Authorization header value without
+// exposing the credential: the username of Basic credentials, the `sub` claim of a Bearer
+// JWT, or else a fingerprint of the token. The claims are not verified, so the result must
+// not be trusted as the identity of the caller.
+func ActorFromAuthorization(authorization string) string {
+	if authorization == "" {
+		return "anonymous"
+	}
+	scheme, credential, found := strings.Cut(authorization, " ")
+	if !found {
+		credential = scheme
+	}
+	switch {
+	case strings.EqualFold(scheme, "Basic"):
+		if decoded, err := base64.StdEncoding.DecodeString(credential); err == nil {
+			if user, _, ok := strings.Cut(string(decoded), ":"); ok && user != "" {
+				return user
+			}
+		}
+	case strings.EqualFold(scheme, "Bearer"):
+		if parts := strings.Split(credential, "."); len(parts) == 3 {
+			if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil {
+				var claims struct {
+					Sub string `json:"sub"`
+				}
+				if json.Unmarshal(payload, &claims) == nil && claims.Sub != "" {
+					return claims.Sub
+				}
+			}
+		}
+	}
+	return credentialFingerprint(authorization)
+}
+
+// FileAuditSink appends audit events as JSON lines to a file.
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileAuditSink opens path in append only mode, creating it if needed.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (s *FileAuditSink) Write(_ context.Context, event *AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(event); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the underlying file.
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// MemoryAuditSink keeps audit events in memory, intended for tests.
+type MemoryAuditSink struct {
+	mu     sync.Mutex
+	events []*AuditEvent
+}
+
+func NewMemoryAuditSink() *MemoryAuditSink {
+	return &MemoryAuditSink{}
+}
+
+func (s *MemoryAuditSink) Write(_ context.Context, event *AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// Events returns a copy of the recorded events.
+func (s *MemoryAuditSink) Events() []*AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*AuditEvent(nil), s.events...)
+}
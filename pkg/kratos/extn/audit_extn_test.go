@@ -0,0 +1,117 @@
+package extn
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	pb "github.com/achuala/kratos-extn/api/gen"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string { return http.Header(hc).Get(key) }
+
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+
+func (hc headerCarrier) Add(key string, value string) { http.Header(hc).Add(key, value) }
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (hc headerCarrier) Values(key string) []string { return http.Header(hc).Values(key) }
+
+type testTransport struct {
+	kind      transport.Kind
+	operation string
+	reqHeader headerCarrier
+	rpyHeader headerCarrier
+}
+
+func newTestTransport(operation string) *testTransport {
+	return &testTransport{
+		kind:      transport.KindHTTP,
+		operation: operation,
+		reqHeader: headerCarrier{},
+		rpyHeader: headerCarrier{},
+	}
+}
+
+func (tr *testTransport) Kind() transport.Kind            { return tr.kind }
+func (tr *testTransport) Endpoint() string                { return "" }
+func (tr *testTransport) Operation() string               { return tr.operation }
+func (tr *testTransport) RequestHeader() transport.Header { return tr.reqHeader }
+func (tr *testTransport) ReplyHeader() transport.Header   { return tr.rpyHeader }
+
+func TestAudit(t *testing.T) {
+	sink := NewMemoryAuditSink()
+	tr := newTestTransport("/options.AuditTestService/Update")
+	tr.reqHeader.Set(string(CtxAuthorizationKey), "Basic dXNlcjpwYXNz")
+	ctx := NewIdentityContext(transport.NewServerContext(context.Background(), tr), &RequestIdentity{UserId: "user"})
+	req := &pb.AuditTestRequest{Target: &pb.AuditTestTarget{Id: "acc-1"}, Secret: "top-secret"}
+
+	handler := Audit(sink)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.Forbidden("FORBIDDEN", "denied")
+	})
+	if _, err := handler(ctx, req); err == nil {
+		t.Fatal("expected handler error to be returned")
+	}
+	events := sink.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Actor != "user" || event.Resource != "acc-1" || event.Outcome != AuditOutcomeFailure || event.Reason != "FORBIDDEN" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if strings.Contains(event.Request, "top-secret") {
+		t.Fatalf("sensitive data not redacted: %s", event.Request)
+	}
+
+	handler = Audit(sink)(func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	_, _ = handler(transport.NewServerContext(context.Background(), newTestTransport("/options.Other/Get")), req)
+	if len(sink.Events()) != 1 {
+		t.Fatal("expected method without annotation not to be audited")
+	}
+}
+
+func TestAuditActor(t *testing.T) {
+	sink := NewMemoryAuditSink()
+	tr := newTestTransport("/options.AuditTestService/Update")
+	// {"sub":"alice"}, unverified
+	tr.reqHeader.Set(string(CtxAuthorizationKey), "Bearer eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9.sig")
+	ctx := transport.NewServerContext(context.Background(), tr)
+	req := &pb.AuditTestRequest{Target: &pb.AuditTestTarget{Id: "acc-1"}}
+
+	_, _ = Audit(sink)(func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })(ctx, req)
+	_, _ = Audit(sink)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		SetAuditActor(ctx, "bob")
+		return nil, nil
+	})(ctx, req)
+	events := sink.Events()
+	if len(events) != 2 || !strings.HasPrefix(events[0].Actor, "token:") || events[1].Actor != "bob" {
+		t.Fatalf("expected the unverified subject not to be the actor, got %+v", events)
+	}
+}
+
+func TestActorFromAuthorization(t *testing.T) {
+	// {"sub":"alice"}
+	jwt := "Bearer eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9.sig"
+	if actor := ActorFromAuthorization(jwt); actor != "alice" {
+		t.Fatalf("expected alice, got %s", actor)
+	}
+	if actor := ActorFromAuthorization("Bearer opaque"); !strings.HasPrefix(actor, "token:") {
+		t.Fatalf("expected token fingerprint, got %s", actor)
+	}
+	if actor := ActorFromAuthorization(""); actor != "anonymous" {
+		t.Fatalf("expected anonymous, got %s", actor)
+	}
+}
@@ -0,0 +1,270 @@
+package extn
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// ReasonCircuitOpen is the error reason of calls rejected by an open circuit breaker.
+const ReasonCircuitOpen = "CIRCUIT_OPEN"
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// BreakerOption is a circuit breaker option.
+type BreakerOption func(*breakerOptions)
+
+type breakerOptions struct {
+	window        time.Duration
+	minRequests   int
+	failureRatio  float64
+	openTimeout   time.Duration
+	halfOpenCalls int
+	onStateChange func(name string, from, to BreakerState)
+}
+
+// WithBreakerWindow sets the interval over which the failure ratio is computed, 10s by default.
+func WithBreakerWindow(window time.Duration) BreakerOption {
+	return func(o *breakerOptions) {
+		o.window = window
+	}
+}
+
+// WithBreakerThreshold opens the breaker when at least minRequests were made in the window
+// and the ratio of failures reaches failureRatio, 20 requests and 0.5 by default.
+func WithBreakerThreshold(minRequests int, failureRatio float64) BreakerOption {
+	return func(o *breakerOptions) {
+		o.minRequests = minRequests
+		o.failureRatio = failureRatio
+	}
+}
+
+// WithBreakerOpenTimeout sets how long the breaker stays open before letting trial calls
+// through, 30s by default.
+func WithBreakerOpenTimeout(timeout time.Duration) BreakerOption {
+	return func(o *breakerOptions) {
+		o.openTimeout = timeout
+	}
+}
+
+// WithBreakerHalfOpenCalls sets the number of successful trial calls closing the breaker, 1 by default.
+func WithBreakerHalfOpenCalls(calls int) BreakerOption {
+	return func(o *breakerOptions) {
+		o.halfOpenCalls = calls
+	}
+}
+
+// WithBreakerStateChange sets the callback invoked on every state transition, e.g. for metrics.
+func WithBreakerStateChange(fn func(name string, from, to BreakerState)) BreakerOption {
+	return func(o *breakerOptions) {
+		o.onStateChange = fn
+	}
+}
+
+// circuitBreaker is a closed/open/half-open breaker over a fixed failure ratio window
+type circuitBreaker struct {
+	name string
+	opts *breakerOptions
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	openedAt    time.Time
+	requests    int
+	failures    int
+	inFlight    int
+	successes   int
+	// generation changes on every state transition, outcomes of calls allowed in an earlier
+	// generation are ignored
+	generation uint64
+	// transitions are passed to onStateChange once mu is released
+	transitions []breakerTransition
+}
+
+type breakerTransition struct {
+	from, to BreakerState
+}
+
+// allow reports whether a call may proceed and returns the generation to pass to done
+func (b *circuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.notify()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.opts.openTimeout {
+			return 0, errors.ServiceUnavailable(ReasonCircuitOpen, "circuit breaker is open for "+b.name)
+		}
+		b.setState(BreakerHalfOpen, now)
+		fallthrough
+	case BreakerHalfOpen:
+		if b.inFlight >= b.opts.halfOpenCalls {
+			return 0, errors.ServiceUnavailable(ReasonCircuitOpen, "circuit breaker is half-open for "+b.name)
+		}
+		b.inFlight++
+	default:
+		if now.Sub(b.windowStart) >= b.opts.window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	}
+	return b.generation, nil
+}
+
+// done records the outcome of a call allowed in generation
+func (b *circuitBreaker) done(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.notify()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	now := time.Now()
+	switch b.state {
+	case BreakerHalfOpen:
+		b.inFlight--
+		if failed {
+			b.setState(BreakerOpen, now)
+			return
+		}
+		if b.successes++; b.successes >= b.opts.halfOpenCalls {
+			b.setState(BreakerClosed, now)
+		}
+	case BreakerClosed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.opts.minRequests && float64(b.failures)/float64(b.requests) >= b.opts.failureRatio {
+			b.setState(BreakerOpen, now)
+		}
+	}
+}
+
+func (b *circuitBreaker) setState(state BreakerState, now time.Time) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.windowStart, b.requests, b.failures, b.successes, b.inFlight = now, 0, 0, 0, 0
+	if state == BreakerOpen {
+		b.openedAt = now
+	}
+	if b.opts.onStateChange != nil {
+		b.transitions = append(b.transitions, breakerTransition{from: from, to: state})
+	}
+}
+
+// notify passes the pending transitions to onStateChange, outside of mu so the callback may
+// use the breaker and a slow callback doesn't hold up calls
+func (b *circuitBreaker) notify() {
+	b.mu.Lock()
+	transitions := b.transitions
+	b.transitions = nil
+	b.mu.Unlock()
+	for _, tr := range transitions {
+		b.opts.onStateChange(b.name, tr.from, tr.to)
+	}
+}
+
+// breakerGroup holds one breaker per key, such as a host or an operation
+type breakerGroup struct {
+	opts     *breakerOptions
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func newBreakerGroup(opts []BreakerOption) *breakerGroup {
+	o := &breakerOptions{
+		window:        time.Second * 10,
+		minRequests:   20,
+		failureRatio:  0.5,
+		openTimeout:   time.Second * 30,
+		halfOpenCalls: 1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &breakerGroup{opts: o, breakers: make(map[string]*circuitBreaker)}
+}
+
+func (g *breakerGroup) get(name string) *circuitBreaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	b, ok := g.breakers[name]
+	if !ok {
+		b = &circuitBreaker{name: name, opts: g.opts, windowStart: time.Now()}
+		g.breakers[name] = b
+	}
+	return b
+}
+
+// ClientCircuitBreaker is a client middleware with one breaker per operation. Calls failing
+// with a 5xx kratos error, or a non kratos error, count as failures.
+func ClientCircuitBreaker(opts ...BreakerOption) middleware.Middleware {
+	group := newBreakerGroup(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			tr, ok := transport.FromClientContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			b := group.get(tr.Operation())
+			generation, err := b.allow()
+			if err != nil {
+				return nil, err
+			}
+			reply, err = handler(ctx, req)
+			b.done(generation, err != nil && !errors.Is(err, context.Canceled) && errors.FromError(err).Code >= http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// WithHttpCircuitBreaker enables one circuit breaker per downstream host in the HTTP client,
+// responses with a 5xx status and connection errors count as failures.
+func WithHttpCircuitBreaker(opts ...BreakerOption) ClientOption {
+	return func(o *clientOptions) {
+		o.breaker = newBreakerGroup(opts)
+	}
+}
+
+type breakerTransport struct {
+	next  http.RoundTripper
+	group *breakerGroup
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.group.get(req.URL.Host)
+	generation, err := b.allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(req)
+	b.done(generation, err != nil || res.StatusCode >= http.StatusInternalServerError)
+	return res, err
+}
@@ -0,0 +1,109 @@
+package extn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var transitions []BreakerState
+	m := ClientCircuitBreaker(
+		WithBreakerThreshold(2, 0.5),
+		WithBreakerOpenTimeout(time.Millisecond*20),
+		WithBreakerStateChange(func(name string, from, to BreakerState) {
+			transitions = append(transitions, to)
+		}),
+	)
+	fail := true
+	calls := 0
+	handler := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		if fail {
+			return nil, errors.ServiceUnavailable("DOWN", "down")
+		}
+		return "ok", nil
+	})
+	ctx := transport.NewClientContext(context.Background(), newTestTransport("/partner.Api/Get"))
+
+	_, _ = handler(ctx, nil)
+	_, _ = handler(ctx, nil)
+	_, err := handler(ctx, nil)
+	if errors.Reason(err) != ReasonCircuitOpen || calls != 2 {
+		t.Fatalf("expected the open breaker to fail fast, got %v after %d calls", err, calls)
+	}
+
+	time.Sleep(time.Millisecond * 30)
+	fail = false
+	if _, err := handler(ctx, nil); err != nil {
+		t.Fatalf("expected the trial call to pass, got %v", err)
+	}
+	expected := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected transitions %v, got %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Fatalf("expected transitions %v, got %v", expected, transitions)
+		}
+	}
+}
+
+func TestCircuitBreakerStaleOutcome(t *testing.T) {
+	group := newBreakerGroup([]BreakerOption{WithBreakerThreshold(1, 1), WithBreakerOpenTimeout(time.Millisecond)})
+	b := group.get("downstream")
+	slow, err := b.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, _ := b.allow()
+	b.done(failing, true)
+	time.Sleep(time.Millisecond * 5)
+	trial, err := b.allow()
+	if err != nil || b.state != BreakerHalfOpen {
+		t.Fatalf("expected a half-open trial call, got %v in state %s", err, b.state)
+	}
+
+	// the call allowed while closed finishes during the trial
+	b.done(slow, false)
+	if b.state != BreakerHalfOpen {
+		t.Fatalf("expected stale outcome to be ignored, got state %s", b.state)
+	}
+	if _, err := b.allow(); err == nil {
+		t.Fatal("expected stale outcome not to free a trial slot")
+	}
+	b.done(trial, false)
+	if b.state != BreakerClosed {
+		t.Fatalf("expected successful trial to close the breaker, got %s", b.state)
+	}
+}
+
+func TestCircuitBreakerStateChangeUnlocked(t *testing.T) {
+	var group *breakerGroup
+	var states []BreakerState
+	group = newBreakerGroup([]BreakerOption{WithBreakerThreshold(1, 1), WithBreakerStateChange(func(name string, from, to BreakerState) {
+		// the callback may use the breaker
+		b := group.get(name)
+		b.mu.Lock()
+		states = append(states, b.state)
+		b.mu.Unlock()
+	})})
+	b := group.get("downstream")
+	generation, _ := b.allow()
+	done := make(chan struct{})
+	go func() {
+		b.done(generation, true)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the state change callback not to deadlock")
+	}
+	if len(states) != 1 || states[0] != BreakerOpen {
+		t.Fatalf("expected the callback to see the open breaker, got %v", states)
+	}
+}
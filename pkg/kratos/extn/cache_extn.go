@@ -0,0 +1,377 @@
+package extn
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Results recorded by the cache counter.
+const (
+	CacheResultHit         = "hit"
+	CacheResultMiss        = "miss"
+	CacheResultRevalidated = "revalidated"
+)
+
+// CachedResponse is a response kept by an HttpCacheStore.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Vary holds the request header values named by the Vary response header.
+	Vary http.Header
+	// StoredAt is when the response was received or last revalidated.
+	StoredAt time.Time
+}
+
+// size approximates the memory used by r
+func (r *CachedResponse) size() int64 {
+	size := int64(len(r.Body))
+	for _, h := range []http.Header{r.Header, r.Vary} {
+		for k, vs := range h {
+			for _, v := range vs {
+				size += int64(len(k) + len(v))
+			}
+		}
+	}
+	return size
+}
+
+// HttpCacheStore keeps the responses cached by the HTTP client, keyed by request URL.
+type HttpCacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, res *CachedResponse)
+	Delete(key string)
+}
+
+// LRUHttpCacheStore is an in-memory HttpCacheStore evicting the least recently used
+// responses once their total size exceeds a limit.
+type LRUHttpCacheStore struct {
+	maxBytes int64
+	mu       sync.Mutex
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type lruEntry struct {
+	key string
+	res *CachedResponse
+}
+
+// NewLRUHttpCacheStore returns a store holding up to maxBytes of responses.
+func NewLRUHttpCacheStore(maxBytes int64) *LRUHttpCacheStore {
+	return &LRUHttpCacheStore{maxBytes: maxBytes, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (s *LRUHttpCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).res, true
+}
+
+func (s *LRUHttpCacheStore) Set(key string, res *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(key)
+	size := res.size()
+	if size > s.maxBytes {
+		return
+	}
+	s.entries[key] = s.order.PushFront(&lruEntry{key: key, res: res})
+	s.size += size
+	for s.size > s.maxBytes {
+		s.remove(s.order.Back().Value.(*lruEntry).key)
+	}
+}
+
+func (s *LRUHttpCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(key)
+}
+
+func (s *LRUHttpCacheStore) remove(key string) {
+	elem, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	s.order.Remove(elem)
+	delete(s.entries, key)
+	s.size -= elem.Value.(*lruEntry).res.size()
+}
+
+// CacheOption is a WithHttpCache option.
+type CacheOption func(*cacheTransport)
+
+// WithCacheCounter sets a counter incremented for every GET request with the "result"
+// attribute set to hit, miss or revalidated, and the "host" attribute.
+func WithCacheCounter(counter metric.Int64Counter) CacheOption {
+	return func(t *cacheTransport) {
+		t.counter = counter
+	}
+}
+
+// WithCacheMaxEntrySize sets the largest response body cached, 1MiB by default.
+func WithCacheMaxEntrySize(size int64) CacheOption {
+	return func(t *cacheTransport) {
+		t.maxEntrySize = size
+	}
+}
+
+// WithHttpCache caches the responses to GET requests in store following their Cache-Control
+// and Expires headers, as a shared cache: private responses and responses to requests with an
+// Authorization header, unless public, are not stored. Stale responses with an ETag or a
+// Last-Modified header are revalidated with a conditional request. Successful unsafe requests
+// invalidate the cached URL.
+func WithHttpCache(store HttpCacheStore, opts ...CacheOption) ClientOption {
+	return func(o *clientOptions) {
+		t := &cacheTransport{store: store, maxEntrySize: 1 << 20}
+		for _, opt := range opts {
+			opt(t)
+		}
+		o.cache = t
+	}
+}
+
+// cacheControl parses a Cache-Control header into its directives
+func cacheControl(h http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// freshness returns how long res stays fresh after it was stored
+func freshness(res *CachedResponse) time.Duration {
+	cc := cacheControl(res.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return 0
+	}
+	var lifetime time.Duration
+	if maxAge, ok := cc["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return 0
+		}
+		lifetime = time.Duration(seconds) * time.Second
+	} else if expires, err := http.ParseTime(res.Header.Get("Expires")); err == nil {
+		date, err := http.ParseTime(res.Header.Get("Date"))
+		if err != nil {
+			date = res.StoredAt
+		}
+		lifetime = expires.Sub(date)
+	}
+	if age, err := strconv.Atoi(res.Header.Get("Age")); err == nil {
+		lifetime -= time.Duration(age) * time.Second
+	}
+	return lifetime
+}
+
+// validated reports whether res can be revalidated
+func validated(res *CachedResponse) bool {
+	return res.Header.Get("ETag") != "" || res.Header.Get("Last-Modified") != ""
+}
+
+// storable reports whether res to req may be cached. The client is shared by the callers of
+// a service, so private responses and responses to authorized requests not marked public are
+// never stored.
+func storable(req *http.Request, res *http.Response) bool {
+	if res.StatusCode != http.StatusOK || res.Header.Get("Vary") == "*" {
+		return false
+	}
+	cc := cacheControl(res.Header)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	if _, ok := cc["private"]; ok {
+		return false
+	}
+	if req.Header.Get("Authorization") != "" {
+		_, public := cc["public"]
+		_, sharedMaxAge := cc["s-maxage"]
+		if !public && !sharedMaxAge {
+			return false
+		}
+	}
+	_, maxAge := cc["max-age"]
+	return maxAge || res.Header.Get("Expires") != "" || res.Header.Get("ETag") != "" || res.Header.Get("Last-Modified") != ""
+}
+
+// matches reports whether req sends the header values res varies on
+func (r *CachedResponse) matches(req *http.Request) bool {
+	for name, values := range r.Vary {
+		if strings.Join(req.Header.Values(name), ",") != strings.Join(values, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// response builds the response served to req from r
+func (r *CachedResponse) response(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(time.Since(r.StoredAt).Seconds())))
+	return &http.Response{
+		Status:        strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+type cacheTransport struct {
+	next         http.RoundTripper
+	store        HttpCacheStore
+	counter      metric.Int64Counter
+	maxEntrySize int64
+}
+
+func (t *cacheTransport) record(req *http.Request, result string) {
+	if t.counter != nil {
+		t.counter.Add(context.Background(), 1, metric.WithAttributes(
+			attribute.String("result", result), attribute.String("host", req.URL.Host)))
+	}
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.URL.String()
+	if req.Method != http.MethodGet {
+		res, err := t.next.RoundTrip(req)
+		if err == nil && req.Method != http.MethodHead && req.Method != http.MethodOptions && res.StatusCode < 400 {
+			t.store.Delete(key)
+		}
+		return res, err
+	}
+	reqCC := cacheControl(req.Header)
+	if _, ok := reqCC["no-store"]; ok {
+		return t.next.RoundTrip(req)
+	}
+	cached, ok := t.store.Get(key)
+	if ok && !cached.matches(req) {
+		cached, ok = nil, false
+	}
+	if ok {
+		_, noCache := reqCC["no-cache"]
+		if !noCache && time.Since(cached.StoredAt) < freshness(cached) {
+			t.record(req, CacheResultHit)
+			return cached.response(req), nil
+		}
+		if validated(cached) && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+			conditional := req.Clone(req.Context())
+			if etag := cached.Header.Get("ETag"); etag != "" {
+				conditional.Header.Set("If-None-Match", etag)
+			}
+			if modified := cached.Header.Get("Last-Modified"); modified != "" {
+				conditional.Header.Set("If-Modified-Since", modified)
+			}
+			res, err := t.next.RoundTrip(conditional)
+			if err != nil {
+				return nil, err
+			}
+			if res.StatusCode == http.StatusNotModified {
+				res.Body.Close()
+				refreshed := *cached
+				refreshed.Header = cached.Header.Clone()
+				for _, name := range []string{"Cache-Control", "Date", "Expires", "ETag", "Last-Modified"} {
+					if value := res.Header.Get(name); value != "" {
+						refreshed.Header.Set(name, value)
+					}
+				}
+				refreshed.Header.Del("Age")
+				refreshed.StoredAt = time.Now()
+				t.store.Set(key, &refreshed)
+				t.record(req, CacheResultRevalidated)
+				return refreshed.response(req), nil
+			}
+			t.record(req, CacheResultMiss)
+			return t.capture(key, req, res), nil
+		}
+	}
+	t.record(req, CacheResultMiss)
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.capture(key, req, res), nil
+}
+
+// capture stores res once its body has been fully read, when it is cacheable
+func (t *cacheTransport) capture(key string, req *http.Request, res *http.Response) *http.Response {
+	if !storable(req, res) {
+		if res.StatusCode != http.StatusNotModified {
+			t.store.Delete(key)
+		}
+		return res
+	}
+	if res.ContentLength > t.maxEntrySize {
+		return res
+	}
+	cached := &CachedResponse{StatusCode: res.StatusCode, Header: res.Header.Clone()}
+	if vary := res.Header.Get("Vary"); vary != "" {
+		cached.Vary = make(http.Header)
+		for _, name := range strings.Split(vary, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cached.Vary[http.CanonicalHeaderKey(name)] = req.Header.Values(name)
+			}
+		}
+	}
+	res.Body = &captureBody{ReadCloser: res.Body, limit: t.maxEntrySize, done: func(body []byte) {
+		cached.Body = body
+		cached.StoredAt = time.Now()
+		t.store.Set(key, cached)
+	}}
+	return res
+}
+
+// captureBody copies the body read by the caller and passes it to done at EOF, unless it
+// exceeds limit
+type captureBody struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	limit    int64
+	overflow bool
+	once     sync.Once
+	done     func([]byte)
+}
+
+func (c *captureBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if !c.overflow {
+		if int64(c.buf.Len()+n) > c.limit {
+			c.overflow = true
+			c.buf = bytes.Buffer{}
+		} else {
+			c.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !c.overflow {
+		c.once.Do(func() { c.done(c.buf.Bytes()) })
+	}
+	return n, err
+}
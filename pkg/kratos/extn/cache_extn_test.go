@@ -0,0 +1,148 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+)
+
+type resultCounter struct {
+	embedded.Int64Counter
+	mu      sync.Mutex
+	results map[string]int64
+}
+
+func (c *resultCounter) Add(_ context.Context, incr int64, opts ...metric.AddOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	attrs := metric.NewAddConfig(opts).Attributes()
+	result, _ := attrs.Value(attribute.Key("result"))
+	c.results[result.AsString()] += incr
+}
+
+func TestHttpCache(t *testing.T) {
+	var requests, conditional int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				conditional++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		_, _ = w.Write([]byte(`{"id":"` + r.URL.Path + `"}`))
+	}))
+	defer srv.Close()
+
+	counter := &resultCounter{results: make(map[string]int64)}
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger,
+		WithHttpCache(NewLRUHttpCacheStore(1<<20), WithCacheCounter(counter)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(method, path string) string {
+		req, _ := http.NewRequest(method, srv.URL+path, nil)
+		res, err := hc.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+
+	for _, path := range []string{"/fresh", "/etag", "/nostore"} {
+		first, second := get(http.MethodGet, path), get(http.MethodGet, path)
+		if first != second || first != `{"id":"`+path+`"}` {
+			t.Fatalf("%s: unexpected bodies %q and %q", path, first, second)
+		}
+	}
+	if requests != 5 || conditional != 1 {
+		t.Fatalf("expected 5 requests with 1 conditional, got %d and %d", requests, conditional)
+	}
+	if counter.results[CacheResultHit] != 1 || counter.results[CacheResultRevalidated] != 1 || counter.results[CacheResultMiss] != 4 {
+		t.Fatalf("unexpected cache results %v", counter.results)
+	}
+
+	get(http.MethodPut, "/fresh")
+	get(http.MethodGet, "/fresh")
+	if requests != 7 {
+		t.Fatalf("expected PUT to invalidate the cached response, got %d requests", requests)
+	}
+}
+
+func TestLRUHttpCacheStore(t *testing.T) {
+	store := NewLRUHttpCacheStore(10)
+	store.Set("a", &CachedResponse{Body: []byte("aaaa")})
+	store.Set("b", &CachedResponse{Body: []byte("bbbb")})
+	store.Get("a")
+	store.Set("c", &CachedResponse{Body: []byte("cccc")})
+	if _, ok := store.Get("b"); ok {
+		t.Fatal("expected least recently used entry to be evicted")
+	}
+	if _, ok := store.Get("a"); !ok {
+		t.Fatal("expected recently used entry to be kept")
+	}
+	store.Set("d", &CachedResponse{Body: []byte("too large body")})
+	if _, ok := store.Get("d"); ok {
+		t.Fatal("expected entry over the size limit not to be stored")
+	}
+}
+
+func TestHttpCacheAuthorization(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/public":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		default:
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger, WithHttpCache(NewLRUHttpCacheStore(1<<20)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path, authorization string) string {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		res, err := hc.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+
+	if get("/user", "Bearer alice") != "Bearer alice" || get("/user", "Bearer bob") != "Bearer bob" {
+		t.Fatal("expected the reply to an authorized request not to be shared")
+	}
+	if get("/private", "") != "" || get("/private", "Bearer bob") != "Bearer bob" {
+		t.Fatal("expected private responses not to be stored")
+	}
+	if get("/public", "Bearer alice") != "Bearer alice" || get("/public", "Bearer bob") != "Bearer alice" {
+		t.Fatal("expected public responses to be shared")
+	}
+}
@@ -0,0 +1,34 @@
+package extn
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/achuala/kratos-extn/pkg/crypto"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestHashChainLogger(t *testing.T) {
+	key := []byte("secret")
+	var buf bytes.Buffer
+	logger := log.With(NewHashChainLogger(NewJSONLogger(&buf), key), "service", "test")
+	for i := 0; i < 3; i++ {
+		_ = logger.Log(log.LevelInfo, "msg", "record", "n", i)
+	}
+	if broken, err := crypto.VerifyChain(bytes.NewReader(buf.Bytes()), key, nil); err != nil || broken != nil {
+		t.Fatalf("expected intact chain, got %v %v", broken, err)
+	}
+
+	tampered := strings.Replace(buf.String(), `"n":"1"`, `"n":"7"`, 1)
+	broken, err := crypto.VerifyChain(strings.NewReader(tampered), key, nil)
+	if err != nil || broken == nil || broken.Line != 2 {
+		t.Fatalf("expected broken link at line 2, got %v %v", broken, err)
+	}
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	removed := lines[0] + lines[2]
+	if broken, _ := crypto.VerifyChain(strings.NewReader(removed), key, nil); broken == nil || broken.Line != 2 {
+		t.Fatalf("expected deleted record to break the chain, got %v", broken)
+	}
+}
@@ -0,0 +1,169 @@
+package extn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	kjson "github.com/go-kratos/kratos/v2/encoding/json"
+	kproto "github.com/go-kratos/kratos/v2/encoding/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// jsonCodec is the kratos json codec with its own protojson options, so a client can be
+// configured without changing the package level kjson options shared by every component.
+type jsonCodec struct {
+	marshalOptions   protojson.MarshalOptions
+	unmarshalOptions protojson.UnmarshalOptions
+}
+
+func (c jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case json.Marshaler:
+		return m.MarshalJSON()
+	case proto.Message:
+		return c.marshalOptions.Marshal(m)
+	default:
+		return json.Marshal(m)
+	}
+}
+
+func (c jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case json.Unmarshaler:
+		return m.UnmarshalJSON(data)
+	case proto.Message:
+		return c.unmarshalOptions.Unmarshal(data, m)
+	default:
+		rv := reflect.ValueOf(v)
+		for rv := rv; rv.Kind() == reflect.Ptr; {
+			if rv.IsNil() {
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		if m, ok := reflect.Indirect(rv).Interface().(proto.Message); ok {
+			return c.unmarshalOptions.Unmarshal(data, m)
+		}
+		return json.Unmarshal(data, m)
+	}
+}
+
+func (jsonCodec) Name() string {
+	return kjson.Name
+}
+
+// defaultJsonCodec uses proto field names and discards unknown fields
+func defaultJsonCodec() jsonCodec {
+	return jsonCodec{
+		marshalOptions:   protojson.MarshalOptions{UseProtoNames: true},
+		unmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+	}
+}
+
+// codecFor returns the codec of a content type, using c for json
+func (c jsonCodec) codecFor(contentType string) encoding.Codec {
+	name := contentSubtype(contentType)
+	if name == kjson.Name || name == "" {
+		return c
+	}
+	if name == "x-protobuf" {
+		name = kproto.Name
+	}
+	if codec := encoding.GetCodec(name); codec != nil {
+		return codec
+	}
+	return c
+}
+
+func (c jsonCodec) requestEncoder(_ context.Context, contentType string, in interface{}) ([]byte, error) {
+	return c.codecFor(contentType).Marshal(in)
+}
+
+func (c jsonCodec) responseDecoder(_ context.Context, res *http.Response, v interface{}) error {
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	return c.codecFor(res.Header.Get("Content-Type")).Unmarshal(data, v)
+}
+
+// codecKey holds the requestCodec of a request made by the request helpers
+type codecKey struct{}
+
+// requestCodec lets the request helpers use the codec of a NewHttpClient client, which they
+// can't get from the *khttp.Client. The helpers encode the request value with the default
+// codec so that any client can send it, the client transport encodes it again with the
+// client codec and records that codec to decode the response.
+type requestCodec struct {
+	value       interface{}
+	contentType string
+	encode      bool
+	client      *jsonCodec
+}
+
+// codecTransport applies the client codec to the requests of the request helpers
+type codecTransport struct {
+	next  http.RoundTripper
+	codec jsonCodec
+}
+
+func (t *codecTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rc, ok := req.Context().Value(codecKey{}).(*requestCodec)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	rc.client = &t.codec
+	if rc.encode {
+		data, err := t.codec.codecFor(rc.contentType).Marshal(rc.value)
+		if err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// contentSubtype returns the codec name of a content type, e.g. json for
+// application/json; charset=utf-8 or application/problem+json
+func contentSubtype(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	_, subtype, found := strings.Cut(strings.TrimSpace(mediaType), "/")
+	if !found {
+		return ""
+	}
+	if i := strings.LastIndex(subtype, "+"); i >= 0 {
+		return subtype[i+1:]
+	}
+	return subtype
+}
+
+// unmarshalInto decodes data into a new T, allocating the value T points to so that
+// codecs requiring a proto.Message receive one
+func unmarshalInto[T any](codec encoding.Codec, data []byte) (T, error) {
+	var out T
+	if rt := reflect.TypeOf(out); rt != nil && rt.Kind() == reflect.Ptr {
+		v := reflect.New(rt.Elem())
+		if err := codec.Unmarshal(data, v.Interface()); err != nil {
+			return out, err
+		}
+		return v.Interface().(T), nil
+	}
+	err := codec.Unmarshal(data, &out)
+	return out, err
+}
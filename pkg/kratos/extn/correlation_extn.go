@@ -0,0 +1,186 @@
+package extn
+
+import (
+	"context"
+	"strings"
+
+	"github.com/achuala/kratos-extn/pkg/snowflake"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/google/uuid"
+)
+
+const maxCorrelationIdLength = 128
+
+// Inbound headers commonly carrying a request identifier, usable as correlation id aliases.
+const (
+	HeaderRequestId   = "X-Request-ID"
+	HeaderAmznTraceId = "X-Amzn-Trace-Id"
+	HeaderTraceparent = "traceparent"
+)
+
+// CorrelationIdGenerator generates a new correlation id.
+type CorrelationIdGenerator func() string
+
+// UUIDv4Generator generates random UUIDs.
+func UUIDv4Generator() CorrelationIdGenerator {
+	return uuid.NewString
+}
+
+// UUIDv7Generator generates time ordered UUIDs, falling back to random UUIDs on error.
+func UUIDv7Generator() CorrelationIdGenerator {
+	return func() string {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return uuid.NewString()
+		}
+		return id.String()
+	}
+}
+
+// SnowflakeGenerator generates ids from the snowflake generator g.
+func SnowflakeGenerator(g *snowflake.Generator) CorrelationIdGenerator {
+	return g.NextString
+}
+
+// CorrelationIdOption is a correlation id middleware option.
+type CorrelationIdOption func(*correlationIdOptions)
+
+type correlationIdOptions struct {
+	generator CorrelationIdGenerator
+	validator func(string) bool
+	aliases   []string
+	echo      bool
+}
+
+// WithCorrelationIdGenerator sets the generator used when the inbound correlation id is
+// missing or invalid, UUIDv4Generator by default.
+func WithCorrelationIdGenerator(generator CorrelationIdGenerator) CorrelationIdOption {
+	return func(o *correlationIdOptions) {
+		o.generator = generator
+	}
+}
+
+// WithCorrelationIdValidator sets the function validating inbound correlation ids, invalid
+// ids are replaced by a generated one. The default accepts up to 128 characters from
+// letters, digits and -_.:/+=@~ so the value can't be used for log injection.
+func WithCorrelationIdValidator(validator func(string) bool) CorrelationIdOption {
+	return func(o *correlationIdOptions) {
+		o.validator = validator
+	}
+}
+
+// WithCorrelationIdAliases sets the headers consulted in order when x-correlation-id is
+// absent or invalid. X-Amzn-Trace-Id contributes its Root and traceparent its trace id.
+func WithCorrelationIdAliases(headers ...string) CorrelationIdOption {
+	return func(o *correlationIdOptions) {
+		o.aliases = headers
+	}
+}
+
+// WithCorrelationIdEcho sets whether the correlation id is written to the reply header,
+// enabled by default.
+func WithCorrelationIdEcho(echo bool) CorrelationIdOption {
+	return func(o *correlationIdOptions) {
+		o.echo = echo
+	}
+}
+
+func getCorrelationIdFromCtx(ctx context.Context) string {
+	if correlationId, ok := ctx.Value(CtxCorrelationIdKey).(string); ok && correlationId != "" {
+		return correlationId
+	}
+	return uuid.NewString()
+}
+
+// isValidCorrelationId reports whether id is non-empty, bounded and only made of
+// characters safe to log.
+func isValidCorrelationId(id string) bool {
+	if len(id) == 0 || len(id) > maxCorrelationIdLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("-_.:/+=@~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// correlationIdFromAlias extracts the identifier carried by an alias header value
+func correlationIdFromAlias(header, value string) string {
+	switch {
+	case strings.EqualFold(header, HeaderAmznTraceId):
+		for _, part := range strings.Split(value, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok && k == "Root" {
+				return v
+			}
+		}
+		return ""
+	case strings.EqualFold(header, HeaderTraceparent):
+		// version-traceid-parentid-flags
+		if parts := strings.Split(value, "-"); len(parts) == 4 && len(parts[1]) == 32 {
+			return parts[1]
+		}
+		return ""
+	}
+	return value
+}
+
+// inboundCorrelationId returns the first valid correlation id found in the request headers
+func (o *correlationIdOptions) inboundCorrelationId(header transport.Header) string {
+	if correlationId := header.Get(string(CtxCorrelationIdKey)); o.validator(correlationId) {
+		return correlationId
+	}
+	for _, alias := range o.aliases {
+		if correlationId := correlationIdFromAlias(alias, header.Get(alias)); o.validator(correlationId) {
+			return correlationId
+		}
+	}
+	return ""
+}
+
+func ClientCorrelationIdInjector() middleware.Middleware {
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				tr.RequestHeader().Set(string(CtxCorrelationIdKey), getCorrelationIdFromCtx(ctx))
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// ServerCorrelationIdInjector stores the inbound correlation id in the context, generating
+// one when the header and its aliases are missing or invalid so that the whole request
+// shares the same id, and echoes it in the reply header.
+func ServerCorrelationIdInjector(opts ...CorrelationIdOption) middleware.Middleware {
+	o := &correlationIdOptions{
+		generator: UUIDv4Generator(),
+		validator: isValidCorrelationId,
+		echo:      true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				correlationId := o.inboundCorrelationId(tr.RequestHeader())
+				if correlationId == "" {
+					correlationId = o.generator()
+				}
+				if o.echo {
+					tr.ReplyHeader().Set(string(CtxCorrelationIdKey), correlationId)
+				}
+				ctx = transport.NewServerContext(context.WithValue(ctx, CtxCorrelationIdKey, correlationId), tr)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package extn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/achuala/kratos-extn/pkg/snowflake"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestServerCorrelationIdInjector(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"valid", "abc-123", true},
+		{"missing", "", false},
+		{"invalid", "abc\n123", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tr := newTestTransport("/test")
+			tr.reqHeader.Set(string(CtxCorrelationIdKey), test.header)
+			ctx := transport.NewServerContext(context.Background(), tr)
+			var first, second string
+			handler := ServerCorrelationIdInjector(WithCorrelationIdGenerator(SnowflakeGenerator(snowflake.New(1))))(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					first, second = getCorrelationIdFromCtx(ctx), getCorrelationIdFromCtx(ctx)
+					return nil, nil
+				})
+			_, _ = handler(ctx, nil)
+			if first == "" || first != second {
+				t.Fatalf("expected a stable correlation id, got %q and %q", first, second)
+			}
+			if (first == test.header) != test.keep {
+				t.Fatalf("unexpected correlation id %q for header %q", first, test.header)
+			}
+		})
+	}
+}
+
+func TestServerCorrelationIdAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected string
+	}{
+		{"request id", HeaderRequestId, "req-1", "req-1"},
+		{"amzn", HeaderAmznTraceId, "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1", "1-5759e988-bd862e3fe1be46a994272793"},
+		{"traceparent", HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tr := newTestTransport("/test")
+			tr.reqHeader.Set(test.header, test.value)
+			ctx := transport.NewServerContext(context.Background(), tr)
+			var correlationId string
+			handler := ServerCorrelationIdInjector(WithCorrelationIdAliases(HeaderRequestId, HeaderAmznTraceId, HeaderTraceparent))(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					correlationId = getCorrelationIdFromCtx(ctx)
+					return nil, nil
+				})
+			_, _ = handler(ctx, nil)
+			if correlationId != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, correlationId)
+			}
+			if echoed := tr.rpyHeader.Get(string(CtxCorrelationIdKey)); echoed != test.expected {
+				t.Fatalf("expected reply header %q, got %q", test.expected, echoed)
+			}
+		})
+	}
+}
+
+func TestSnowflakeCorrelationIdRoundTrip(t *testing.T) {
+	generate := SnowflakeGenerator(snowflake.New(1))
+	for i := 0; i < 2000; i++ {
+		id := generate()
+		tr := newTestTransport("/test")
+		tr.reqHeader.Set(string(CtxCorrelationIdKey), id)
+		var got string
+		handler := ServerCorrelationIdInjector()(func(ctx context.Context, req interface{}) (interface{}, error) {
+			got = getCorrelationIdFromCtx(ctx)
+			return nil, nil
+		})
+		_, _ = handler(transport.NewServerContext(context.Background(), tr), nil)
+		if got != id {
+			t.Fatalf("expected generated id %q to be kept, got %q", id, got)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package extn
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/wrr"
+)
+
+// ReasonNodeNotFound is the error reason of requests made while no instance is discovered.
+const ReasonNodeNotFound = "NODE_NOT_FOUND"
+
+// defaultBalancer is used when discovery is enabled without WithHttpBalancer
+var defaultBalancer = wrr.NewBuilder()
+
+// WithHttpDiscovery resolves "discovery:///<service>" endpoints through d. Requests with the
+// "discovery" scheme, or without a host, are sent to an instance picked by the balancer. The
+// instances are watched until the WithHttpWatchContext context is done, khttp.Client.Close
+// does not stop the watch.
+func WithHttpDiscovery(d registry.Discovery) ClientOption {
+	return func(o *clientOptions) {
+		o.discovery = d
+	}
+}
+
+// WithHttpBalancer sets the balancer picking the instance of each request, weighted round
+// robin by default, which is plain round robin for instances without a "weight" metadata.
+// The kratos p2c and random builders can be used as well.
+func WithHttpBalancer(b selector.Builder) ClientOption {
+	return func(o *clientOptions) {
+		o.balancer = b
+	}
+}
+
+// WithHttpNodeFilter sets filters applied to the discovered instances on every request,
+// such as the kratos version filter.
+func WithHttpNodeFilter(filters ...selector.NodeFilter) ClientOption {
+	return func(o *clientOptions) {
+		o.nodeFilters = filters
+	}
+}
+
+// WithHttpWatchContext sets the lifetime of the discovery watch, cancel ctx to stop it when
+// the client is no longer used. Without it the watch lasts as long as the process.
+func WithHttpWatchContext(ctx context.Context) ClientOption {
+	return func(o *clientOptions) {
+		o.watchCtx = ctx
+	}
+}
+
+// WithHttpBlock makes NewHttpClient wait, within its context, until instances are discovered.
+// The context only bounds that wait, not the watch.
+func WithHttpBlock() ClientOption {
+	return func(o *clientOptions) {
+		o.block = true
+	}
+}
+
+// nodeResolver keeps the selector of a client up to date with the discovered instances
+type nodeResolver struct {
+	service  string
+	selector selector.Selector
+	scheme   string
+	filters  []selector.NodeFilter
+
+	mu    sync.RWMutex
+	nodes []selector.Node
+}
+
+// newNodeResolver watches service until the watch context is done and waits, within ctx,
+// for its first instances when block is set
+func newNodeResolver(ctx context.Context, service string, o *clientOptions, logger log.Logger) (*nodeResolver, error) {
+	watchCtx, stop := context.WithCancel(o.watchCtx)
+	watcher, err := o.discovery.Watch(watchCtx, service)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	context.AfterFunc(watchCtx, func() { _ = watcher.Stop() })
+	r := &nodeResolver{service: service, selector: o.balancer.Build(), scheme: "http", filters: o.nodeFilters}
+	if o.tlsConf != nil {
+		r.scheme = "https"
+	}
+	ready := make(chan struct{})
+	go func() {
+		var once sync.Once
+		for {
+			instances, err := watcher.Next()
+			if err != nil {
+				if errors.Is(err, context.Canceled) || watchCtx.Err() != nil {
+					stop()
+					return
+				}
+				log.With(logger).Log(log.LevelError, "msg", "failed to watch service instances", "service", service, "error", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			if r.update(instances) {
+				once.Do(func() { close(ready) })
+			}
+		}
+	}()
+	if o.block {
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			stop()
+			return nil, ctx.Err()
+		}
+	}
+	return r, nil
+}
+
+// update applies the instances with an endpoint of the resolver scheme, an empty list is
+// ignored to keep serving from the last known instances
+func (r *nodeResolver) update(instances []*registry.ServiceInstance) bool {
+	nodes := make([]selector.Node, 0, len(instances))
+	for _, ins := range instances {
+		if address := instanceAddress(ins, r.scheme); address != "" {
+			nodes = append(nodes, selector.NewNode(r.scheme, address, ins))
+		}
+	}
+	if len(nodes) == 0 {
+		return false
+	}
+	r.selector.Apply(nodes)
+	r.mu.Lock()
+	r.nodes = nodes
+	r.mu.Unlock()
+	return true
+}
+
+// hosts returns the addresses of the discovered instances
+func (r *nodeResolver) hosts() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hosts := make([]string, len(r.nodes))
+	for i, node := range r.nodes {
+		hosts[i] = node.Address()
+	}
+	return hosts
+}
+
+// instanceAddress returns the host of the first endpoint of ins with scheme
+func instanceAddress(ins *registry.ServiceInstance, scheme string) string {
+	for _, e := range ins.Endpoints {
+		if u, err := url.Parse(e); err == nil && u.Scheme == scheme {
+			return u.Host
+		}
+	}
+	return ""
+}
+
+// discoveryService returns the service name of a "discovery:///<service>" endpoint
+func discoveryService(endpoint string) (string, bool) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "discovery" {
+		return "", false
+	}
+	return strings.TrimPrefix(u.Path, "/"), true
+}
+
+type balancerTransport struct {
+	next     http.RoundTripper
+	resolver *nodeResolver
+}
+
+func (t *balancerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "discovery" && req.URL.Host != "" {
+		return t.next.RoundTrip(req)
+	}
+	node, done, err := t.resolver.selector.Select(req.Context(), selector.WithNodeFilter(t.resolver.filters...))
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, errors.ServiceUnavailable(ReasonNodeNotFound, err.Error())
+	}
+	req = req.Clone(withTarget(req.Context(), t.resolver.service))
+	req.URL.Scheme, req.URL.Host, req.Host = t.resolver.scheme, node.Address(), ""
+	res, err := t.next.RoundTrip(req)
+	if err == nil && res.StatusCode >= http.StatusInternalServerError {
+		done(req.Context(), selector.DoneInfo{Err: errors.New(res.StatusCode, statusReason(res.StatusCode), res.Status)})
+	} else {
+		done(req.Context(), selector.DoneInfo{Err: err})
+	}
+	return res, err
+}
+
+// StaticDiscovery is a registry.Discovery over a fixed, updatable, list of instances, for
+// tests and local runs.
+type StaticDiscovery struct {
+	mu       sync.Mutex
+	services map[string][]*registry.ServiceInstance
+	watchers map[string][]*staticWatcher
+}
+
+// NewStaticDiscovery returns an empty StaticDiscovery.
+func NewStaticDiscovery() *StaticDiscovery {
+	return &StaticDiscovery{
+		services: make(map[string][]*registry.ServiceInstance),
+		watchers: make(map[string][]*staticWatcher),
+	}
+}
+
+// Set replaces the instances of service and notifies its watchers.
+func (d *StaticDiscovery) Set(service string, instances ...*registry.ServiceInstance) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.services[service] = instances
+	for _, w := range d.watchers[service] {
+		select {
+		case w.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SetEndpoints replaces the instances of service by one instance per endpoint, e.g. "http://127.0.0.1:8000".
+func (d *StaticDiscovery) SetEndpoints(service string, endpoints ...string) {
+	instances := make([]*registry.ServiceInstance, len(endpoints))
+	for i, e := range endpoints {
+		instances[i] = &registry.ServiceInstance{ID: e, Name: service, Endpoints: []string{e}}
+	}
+	d.Set(service, instances...)
+}
+
+func (d *StaticDiscovery) GetService(_ context.Context, service string) ([]*registry.ServiceInstance, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.services[service], nil
+}
+
+func (d *StaticDiscovery) Watch(ctx context.Context, service string) (registry.Watcher, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	w := &staticWatcher{discovery: d, service: service, ctx: ctx, cancel: cancel, changed: make(chan struct{}, 1)}
+	if len(d.services[service]) > 0 {
+		w.changed <- struct{}{}
+	}
+	d.watchers[service] = append(d.watchers[service], w)
+	return w, nil
+}
+
+type staticWatcher struct {
+	discovery *StaticDiscovery
+	service   string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	changed   chan struct{}
+}
+
+func (w *staticWatcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case <-w.changed:
+	}
+	return w.discovery.GetService(w.ctx, w.service)
+}
+
+func (w *staticWatcher) Stop() error {
+	w.cancel()
+	w.discovery.mu.Lock()
+	defer w.discovery.mu.Unlock()
+	watchers := w.discovery.watchers[w.service]
+	for i, other := range watchers {
+		if other == w {
+			w.discovery.watchers[w.service] = append(watchers[:i], watchers[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/selector/p2c"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+func newNamedServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(name))
+	}))
+}
+
+func callDiscovered(t *testing.T, hc *khttp.Client, target string) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, target, nil)
+	res, err := hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return string(body)
+}
+
+func TestHttpDiscovery(t *testing.T) {
+	a, b, c := newNamedServer("a"), newNamedServer("b"), newNamedServer("c")
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+	discovery := NewStaticDiscovery()
+	discovery.SetEndpoints("orders", a.URL, b.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	hc, err := NewHttpClient(ctx, "discovery:///orders", log.DefaultLogger, WithHttpDiscovery(discovery), WithHttpBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		seen[callDiscovered(t, hc, "discovery:///v1/orders")]++
+	}
+	if seen["a"] != 2 || seen["b"] != 2 {
+		t.Fatalf("expected requests to be balanced, got %v", seen)
+	}
+
+	discovery.SetEndpoints("orders", c.URL)
+	deadline := time.Now().Add(time.Second)
+	for callDiscovered(t, hc, "/v1/orders") != "c" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected instance updates to be applied")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if got := callDiscovered(t, hc, c.URL); got != "c" {
+		t.Fatalf("expected requests with a host not to be balanced, got %q", got)
+	}
+}
+
+func TestHttpDiscoveryBalancer(t *testing.T) {
+	a := newNamedServer("a")
+	defer a.Close()
+	discovery := NewStaticDiscovery()
+	discovery.SetEndpoints("orders", a.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	hc, err := NewHttpClient(ctx, "discovery:///orders", log.DefaultLogger,
+		WithHttpDiscovery(discovery), WithHttpBalancer(p2c.NewBuilder()), WithHttpBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callDiscovered(t, hc, "/v1/orders"); got != "a" {
+		t.Fatalf("unexpected response %q", got)
+	}
+
+	hc, err = NewHttpClient(ctx, "discovery:///payments", log.DefaultLogger, WithHttpDiscovery(discovery))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/v1/payments", nil)
+	if _, err := hc.Do(req); errors.Reason(err) != ReasonNodeNotFound {
+		t.Fatalf("expected %s without instances, got %v", ReasonNodeNotFound, err)
+	}
+}
+
+func TestHttpDiscoveryWatchLifetime(t *testing.T) {
+	a, b := newNamedServer("a"), newNamedServer("b")
+	defer a.Close()
+	defer b.Close()
+	discovery := NewStaticDiscovery()
+	discovery.SetEndpoints("orders", a.URL)
+
+	watchCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	hc, err := NewHttpClient(ctx, "discovery:///orders", log.DefaultLogger,
+		WithHttpDiscovery(discovery), WithHttpWatchContext(watchCtx), WithHttpBlock())
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the block context is done, updates keep being applied
+	discovery.SetEndpoints("orders", b.URL)
+	deadline := time.Now().Add(time.Second)
+	for callDiscovered(t, hc, "/v1/orders") != "b" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected instance updates after the block context is done")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	stop()
+	deadline = time.Now().Add(time.Second)
+	for {
+		discovery.mu.Lock()
+		watching := len(discovery.watchers["orders"])
+		discovery.mu.Unlock()
+		if watching == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the watcher to stop with its context")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,115 @@
+package extn
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	kgrpc "github.com/go-kratos/kratos/v2/transport/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// GrpcClientOption is a NewGrpcClient option.
+type GrpcClientOption func(*grpcClientOptions)
+
+type grpcClientOptions struct {
+	timeout        time.Duration
+	keepalive      *keepalive.ClientParameters
+	maxRecvMsgSize int
+	maxSendMsgSize int
+	tlsConf        *tls.Config
+	discovery      registry.Discovery
+	identityOpts   []IdentityOption
+}
+
+// WithGrpcTimeout sets the per call timeout, 10s by default.
+func WithGrpcTimeout(timeout time.Duration) GrpcClientOption {
+	return func(o *grpcClientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithGrpcKeepalive enables client keepalive pings, none are sent by default. The server
+// keepalive enforcement policy must permit them, grpc-go servers close connections pinging
+// more often than every 5 minutes, or without active streams, with GOAWAY too_many_pings.
+func WithGrpcKeepalive(params keepalive.ClientParameters) GrpcClientOption {
+	return func(o *grpcClientOptions) {
+		o.keepalive = &params
+	}
+}
+
+// WithGrpcMaxMessageSize sets the maximum size in bytes of received and sent messages.
+func WithGrpcMaxMessageSize(recv, send int) GrpcClientOption {
+	return func(o *grpcClientOptions) {
+		o.maxRecvMsgSize = recv
+		o.maxSendMsgSize = send
+	}
+}
+
+// WithGrpcTLSConfig enables TLS, the connection is insecure otherwise.
+func WithGrpcTLSConfig(conf *tls.Config) GrpcClientOption {
+	return func(o *grpcClientOptions) {
+		o.tlsConf = conf
+	}
+}
+
+// WithGrpcDiscovery resolves discovery:///service endpoints through d.
+func WithGrpcDiscovery(d registry.Discovery) GrpcClientOption {
+	return func(o *grpcClientOptions) {
+		o.discovery = d
+	}
+}
+
+// WithGrpcIdentity sets the ClientIdentityInjector options, to match WithServerIdentity.
+func WithGrpcIdentity(opts ...IdentityOption) GrpcClientOption {
+	return func(o *grpcClientOptions) {
+		o.identityOpts = opts
+	}
+}
+
+// NewGrpcClient returns a gRPC connection to endpoint with the same middleware stack as NewHttpClient.
+func NewGrpcClient(ctx context.Context, endpoint string, logger log.Logger, opts ...GrpcClientOption) (*grpc.ClientConn, error) {
+	o := &grpcClientOptions{
+		timeout:        time.Second * 10,
+		maxRecvMsgSize: 4 * 1024 * 1024,
+		maxSendMsgSize: 4 * 1024 * 1024,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(o.maxRecvMsgSize),
+			grpc.MaxCallSendMsgSize(o.maxSendMsgSize),
+		),
+	}
+	if o.keepalive != nil {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(*o.keepalive))
+	}
+	clientOpts := []kgrpc.ClientOption{
+		kgrpc.WithEndpoint(endpoint),
+		kgrpc.WithMiddleware(clientMiddleware(logger, defaultPropagator, o.identityOpts)...),
+		kgrpc.WithTimeout(o.timeout),
+		kgrpc.WithOptions(dialOpts...),
+	}
+	if o.discovery != nil {
+		clientOpts = append(clientOpts, kgrpc.WithDiscovery(o.discovery))
+	}
+	var (
+		conn *grpc.ClientConn
+		err  error
+	)
+	if o.tlsConf != nil {
+		conn, err = kgrpc.Dial(ctx, append(clientOpts, kgrpc.WithTLSConfig(o.tlsConf))...)
+	} else {
+		conn, err = kgrpc.DialInsecure(ctx, clientOpts...)
+	}
+	if err != nil {
+		log.With(logger).Log(log.LevelError, "failed to initialize grpc client", err)
+		return nil, err
+	}
+	return conn, nil
+}
@@ -0,0 +1,104 @@
+package extn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type grpcCapture struct {
+	correlationId string
+	identity      *RequestIdentity
+}
+
+// startGrpcServer starts a server with the standard stack recording what the client forwarded
+func startGrpcServer(t *testing.T, captured *grpcCapture, opts ...ServerOption) string {
+	t.Helper()
+	capture := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			captured.correlationId = getCorrelationIdFromCtx(ctx)
+			captured.identity, _ = IdentityFromContext(ctx)
+			return handler(ctx, req)
+		}
+	}
+	srv := NewGrpcServer(log.DefaultLogger, append([]ServerOption{
+		WithServerAddress("127.0.0.1:0"),
+		WithSkipAuth("/grpc.health.v1.Health/"),
+		WithServerMiddleware(capture),
+	}, opts...)...)
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	t.Cleanup(func() { _ = srv.Stop(context.Background()) })
+	return endpoint.Host
+}
+
+func TestNewGrpcClient(t *testing.T) {
+	var captured grpcCapture
+	endpoint := startGrpcServer(t, &captured)
+	conn, err := NewGrpcClient(context.Background(), endpoint, log.DefaultLogger, WithGrpcMaxMessageSize(1024, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx := context.WithValue(context.Background(), CtxCorrelationIdKey, "corr-1")
+	ctx = NewIdentityContext(ctx, &RequestIdentity{SystemPeer: "orders", Channel: "web", UserId: "u-1"})
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if captured.correlationId != "corr-1" {
+		t.Fatalf("expected correlation id to be forwarded, got %q", captured.correlationId)
+	}
+	if captured.identity == nil || captured.identity.UserId != "u-1" || captured.identity.SystemPeer != "orders" {
+		t.Fatalf("expected identity to be forwarded, got %+v", captured.identity)
+	}
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: strings.Repeat("a", 128)})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the send size limit to apply, got %v", err)
+	}
+}
+
+func TestNewGrpcClientTLS(t *testing.T) {
+	// borrow the test certificate of an httptest TLS server
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	clientConf := ts.Client().Transport.(*http.Transport).TLSClientConfig
+	var captured grpcCapture
+	endpoint := startGrpcServer(t, &captured, WithServerTLSConfig(ts.TLS))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := NewGrpcClient(ctx, endpoint, log.DefaultLogger, WithGrpcTLSConfig(clientConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("expected TLS connection to succeed, got %v", err)
+	}
+
+	insecure, err := NewGrpcClient(ctx, endpoint, log.DefaultLogger, WithGrpcTimeout(time.Millisecond*500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer insecure.Close()
+	if _, err := healthpb.NewHealthClient(insecure).Check(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+		t.Fatal("expected insecure connection to a TLS server to fail")
+	}
+}
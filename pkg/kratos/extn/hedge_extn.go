@@ -0,0 +1,258 @@
+package extn
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// hedgeSamples is the number of latencies kept to compute the hedging percentile
+	hedgeSamples = 256
+	// hedgeMinSamples is the number of latencies observed before the percentile replaces the fixed delay
+	hedgeMinSamples = 32
+	// hedgeMaxBudget bounds the hedges saved up while the downstream is fast
+	hedgeMaxBudget = 10
+)
+
+// HedgePolicy configures the hedging of read-only requests by the HTTP client.
+type HedgePolicy struct {
+	// Delay is the wait for a response before sending a hedged request.
+	Delay time.Duration
+	// Percentile, between 0 and 1, replaces Delay by that percentile of the observed
+	// latencies once enough were observed, e.g. 0.95.
+	Percentile float64
+	// MaxHedges is the number of hedged requests sent per request, 1 by default.
+	MaxHedges int
+	// MaxFraction caps the hedged requests to this fraction of the requests, 0.1 by default.
+	// Hedges are earned by requests, so a fraction of 0.1 lets every tenth request be hedged.
+	MaxFraction float64
+	// Hosts are the endpoint instances hedged requests are sent to, other than the one of
+	// the original request. Hedged requests go to the original host when empty.
+	Hosts []string
+}
+
+// WithHttpHedging sends hedged copies of GET, HEAD and OPTIONS requests not answered within
+// the policy delay. The first response without a 5xx status is returned and the other
+// requests are cancelled.
+func WithHttpHedging(policy HedgePolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.hedge = &policy
+	}
+}
+
+// hedgeBudget lets a fraction of the requests be hedged
+type hedgeBudget struct {
+	mu       sync.Mutex
+	fraction float64
+	tokens   float64
+}
+
+func (b *hedgeBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.fraction, hedgeMaxBudget)
+}
+
+func (b *hedgeBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// tolerate the rounding of summed fractions, ten deposits of 0.1 make one token
+	if b.tokens < 1-1e-9 {
+		return false
+	}
+	b.tokens = max(b.tokens-1, 0)
+	return true
+}
+
+// latencyTracker computes a percentile over the last observed latencies
+type latencyTracker struct {
+	percentile float64
+	mu         sync.Mutex
+	samples    []time.Duration
+	next       int
+	observed   int
+	value      time.Duration
+}
+
+func (l *latencyTracker) observe(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.samples) < hedgeSamples {
+		l.samples = append(l.samples, latency)
+	} else {
+		l.samples[l.next] = latency
+		l.next = (l.next + 1) % hedgeSamples
+	}
+	// recompute periodically rather than on every request
+	if l.observed++; l.observed%hedgeMinSamples == 0 {
+		sorted := slices.Clone(l.samples)
+		slices.Sort(sorted)
+		l.value = sorted[int(l.percentile*float64(len(sorted)-1))]
+	}
+}
+
+// get returns the percentile, false until enough latencies were observed
+func (l *latencyTracker) get() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.value, l.observed >= hedgeMinSamples
+}
+
+type hedgeTransport struct {
+	next      http.RoundTripper
+	policy    HedgePolicy
+	budget    *hedgeBudget
+	latencies *latencyTracker
+	// hosts returns the endpoint instances hedged requests may be sent to
+	hosts   func() []string
+	counter atomic.Uint64
+}
+
+func newHedgeTransport(next http.RoundTripper, policy HedgePolicy) *hedgeTransport {
+	if policy.MaxHedges <= 0 {
+		policy.MaxHedges = 1
+	}
+	if policy.MaxFraction <= 0 {
+		policy.MaxFraction = 0.1
+	}
+	t := &hedgeTransport{
+		next:      next,
+		policy:    policy,
+		budget:    &hedgeBudget{fraction: policy.MaxFraction},
+		latencies: &latencyTracker{percentile: policy.Percentile},
+	}
+	t.hosts = func() []string { return t.policy.Hosts }
+	return t
+}
+
+func (t *hedgeTransport) delay() time.Duration {
+	if t.policy.Percentile > 0 {
+		if value, ok := t.latencies.get(); ok {
+			return value
+		}
+	}
+	return t.policy.Delay
+}
+
+// pick returns the host of a hedged request, another instance than primary when known
+func (t *hedgeTransport) pick(primary string) string {
+	var candidates []string
+	for _, host := range t.hosts() {
+		if host != primary {
+			candidates = append(candidates, host)
+		}
+	}
+	if len(candidates) == 0 {
+		return primary
+	}
+	return candidates[t.counter.Add(1)%uint64(len(candidates))]
+}
+
+type hedgeResult struct {
+	index  int
+	res    *http.Response
+	err    error
+	cancel context.CancelFunc
+	start  time.Time
+}
+
+func (t *hedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return t.next.RoundTrip(req)
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return t.next.RoundTrip(req)
+	}
+	t.budget.deposit()
+	results := make(chan hedgeResult, 1+t.policy.MaxHedges)
+	var cancels []context.CancelFunc
+	send := func(host string) error {
+		// hedged requests count against the limits of the original downstream
+		ctx, cancel := context.WithCancel(withTarget(req.Context(), req.URL.Host))
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		r := req.Clone(ctx)
+		if host != req.URL.Host {
+			r.URL.Host, r.Host = host, ""
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			r.Body = body
+		}
+		start := time.Now()
+		go func() {
+			res, err := t.next.RoundTrip(r)
+			results <- hedgeResult{index: index, res: res, err: err, cancel: cancel, start: start}
+		}()
+		return nil
+	}
+	if err := send(req.URL.Host); err != nil {
+		return nil, err
+	}
+	sent, inFlight := 1, 1
+	timer := time.NewTimer(t.delay())
+	defer timer.Stop()
+	var last *hedgeResult
+	for inFlight > 0 {
+		select {
+		case <-timer.C:
+			if sent <= t.policy.MaxHedges && t.budget.withdraw() && send(t.pick(req.URL.Host)) == nil {
+				sent++
+				inFlight++
+				timer.Reset(t.delay())
+			}
+		case r := <-results:
+			inFlight--
+			if r.err == nil && r.res.StatusCode < http.StatusInternalServerError {
+				t.latencies.observe(time.Since(r.start))
+				if last != nil {
+					discard(*last)
+				}
+				// cancel the requests still in flight and discard their outcome
+				for i, cancel := range cancels {
+					if i != r.index {
+						cancel()
+					}
+				}
+				go func(n int) {
+					for ; n > 0; n-- {
+						discard(<-results)
+					}
+				}(inFlight)
+				return withCancel(r), nil
+			}
+			if last != nil {
+				discard(*last)
+			}
+			last = &r
+		}
+	}
+	return withCancel(*last), last.err
+}
+
+// withCancel releases the request context of r once its body is closed
+func withCancel(r hedgeResult) *http.Response {
+	if r.res == nil {
+		r.cancel()
+		return nil
+	}
+	r.res.Body = &releaseOnClose{ReadCloser: r.res.Body, release: r.cancel}
+	return r.res
+}
+
+func discard(r hedgeResult) {
+	r.cancel()
+	if r.res != nil {
+		r.res.Body.Close()
+	}
+}
@@ -0,0 +1,102 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+func TestHttpHedging(t *testing.T) {
+	var cancelled atomic.Bool
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			cancelled.Store(true)
+		case <-time.After(time.Second):
+		}
+		_, _ = w.Write([]byte(`slow`))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`fast`))
+	}))
+	defer fast.Close()
+	fastURL, _ := url.Parse(fast.URL)
+
+	get := func(hc *khttp.Client) (string, time.Duration) {
+		start := time.Now()
+		req, _ := http.NewRequest(http.MethodGet, slow.URL, nil)
+		res, err := hc.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return string(body), time.Since(start)
+	}
+
+	hc, err := NewHttpClient(context.Background(), slow.URL, log.DefaultLogger, WithHttpHedging(HedgePolicy{
+		Delay:       time.Millisecond * 20,
+		MaxFraction: 1,
+		Hosts:       []string{fastURL.Host},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, elapsed := get(hc); body != "fast" || elapsed > time.Millisecond*500 {
+		t.Fatalf("expected hedged response, got %q after %s", body, elapsed)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !cancelled.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !cancelled.Load() {
+		t.Fatal("expected the slow request to be cancelled")
+	}
+
+	hc, err = NewHttpClient(context.Background(), slow.URL, log.DefaultLogger, WithHttpHedging(HedgePolicy{
+		Delay:       time.Millisecond * 20,
+		MaxFraction: 0.01,
+		Hosts:       []string{fastURL.Host},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := get(hc); body != "slow" {
+		t.Fatalf("expected no hedging without budget, got %q", body)
+	}
+}
+
+func TestLatencyTracker(t *testing.T) {
+	l := &latencyTracker{percentile: 0.9}
+	for i := 1; i <= hedgeMinSamples*2; i++ {
+		if _, ok := l.get(); ok != (i > hedgeMinSamples) {
+			t.Fatalf("unexpected readiness after %d samples", i-1)
+		}
+		l.observe(time.Duration(i) * time.Millisecond)
+	}
+	if value, _ := l.get(); value != time.Millisecond*57 {
+		t.Fatalf("expected p90 of 57ms, got %s", value)
+	}
+}
+
+func TestHedgePolicyDefaults(t *testing.T) {
+	hedge := newHedgeTransport(http.DefaultTransport, HedgePolicy{Delay: time.Millisecond})
+	if hedge.policy.MaxFraction != 0.1 || hedge.policy.MaxHedges != 1 {
+		t.Fatalf("unexpected defaults %+v", hedge.policy)
+	}
+	for i := 0; i < 10; i++ {
+		hedge.budget.deposit()
+	}
+	if !hedge.budget.withdraw() {
+		t.Fatal("expected one hedge every ten requests by default")
+	}
+}
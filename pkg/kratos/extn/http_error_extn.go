@@ -0,0 +1,87 @@
+package extn
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// Metadata keys of the errors returned by DecodeHttpError.
+const (
+	ErrorMetadataStatus       = "http.status"
+	ErrorMetadataBody         = "http.body"
+	ErrorMetadataHeaderPrefix = "http.header."
+)
+
+const (
+	maxErrorBodySize     = 64 * 1024
+	maxErrorMetadataBody = 1024
+)
+
+// errorMetadataHeaders are the response headers attached to errors, others may carry
+// credentials such as Set-Cookie and would end up in the client logs
+var errorMetadataHeaders = []string{
+	"Content-Type",
+	"Retry-After",
+	HeaderRequestId,
+	HeaderAmznTraceId,
+	string(CtxCorrelationIdKey),
+}
+
+// DecodeHttpError is a khttp.DecodeErrorFunc turning non 2xx responses into *errors.Error.
+// Kratos style JSON error bodies keep their reason, message and metadata, other responses get
+// a reason derived from the status, e.g. SERVICE_UNAVAILABLE. The status, the Content-Type,
+// Retry-After and request id headers and the body truncated to 1KB are attached as metadata.
+// The response body is closed.
+func DecodeHttpError(_ context.Context, res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+
+	var e *errors.Error
+	var body struct {
+		Reason   string            `json:"reason"`
+		Message  string            `json:"message"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	if json.Unmarshal(data, &body) == nil && (body.Reason != "" || body.Message != "") {
+		e = errors.New(res.StatusCode, body.Reason, body.Message)
+	} else {
+		e = errors.New(res.StatusCode, statusReason(res.StatusCode), http.StatusText(res.StatusCode))
+	}
+	md := make(map[string]string, len(body.Metadata)+len(errorMetadataHeaders)+2)
+	for k, v := range body.Metadata {
+		md[k] = v
+	}
+	for _, name := range errorMetadataHeaders {
+		if v := res.Header.Values(name); len(v) > 0 {
+			md[ErrorMetadataHeaderPrefix+http.CanonicalHeaderKey(name)] = strings.Join(v, ", ")
+		}
+	}
+	md[ErrorMetadataStatus] = strconv.Itoa(res.StatusCode)
+	md[ErrorMetadataBody] = truncate(string(data), maxErrorMetadataBody)
+	return e.WithMetadata(md)
+}
+
+// statusReason returns the reason of a status without kratos error body, e.g. NOT_FOUND for 404
+func statusReason(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return errors.UnknownReason
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,60 @@
+package extn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestMakeHTTPRequestErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/kratos":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":404,"reason":"USER_NOT_FOUND","message":"user not found","metadata":{"id":"1"}}`))
+		case "/plain":
+			w.Header().Set(HeaderRequestId, "req-1")
+			w.Header().Set("Set-Cookie", "session=secret")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("maintenance"))
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	_, err = MakeHTTPRequest(ctx, hc, srv.URL+"/kratos", http.MethodGet, nil, nil, nil, map[string]string{})
+	if se := errors.FromError(err); se.Code != http.StatusNotFound || se.Reason != "USER_NOT_FOUND" || se.Metadata["id"] != "1" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err = MakeHTTPRequest(ctx, hc, srv.URL+"/plain", http.MethodGet, nil, nil, nil, map[string]string{})
+	se := errors.FromError(err)
+	if se.Reason != "SERVICE_UNAVAILABLE" || se.Metadata[ErrorMetadataBody] != "maintenance" || se.Metadata[ErrorMetadataHeaderPrefix+"X-Request-Id"] != "req-1" {
+		t.Fatalf("unexpected error %v %v", err, se.Metadata)
+	}
+	if _, ok := se.Metadata[ErrorMetadataHeaderPrefix+"Set-Cookie"]; ok {
+		t.Fatalf("expected Set-Cookie not to be attached, got %v", se.Metadata)
+	}
+
+	created, err := MakeHTTPRequest(ctx, hc, srv.URL+"/created", http.MethodPost, nil, nil, nil, map[string]string{})
+	if err != nil || created["id"] != "1" {
+		t.Fatalf("expected 201 to succeed, got %v %v", created, err)
+	}
+	if _, err = MakeHTTPRequest(ctx, hc, srv.URL+"/empty", http.MethodDelete, nil, nil, nil, map[string]string{}); err != nil {
+		t.Fatalf("expected 204 to succeed, got %v", err)
+	}
+}
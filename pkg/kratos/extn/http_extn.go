@@ -3,22 +3,17 @@ package extn
 import (
 	"context"
 	"net/http"
-	"time"
 
-	kjson "github.com/go-kratos/kratos/v2/encoding/json"
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport"
-	"github.com/google/uuid"
-	"go.opentelemetry.io/contrib/propagators/b3"
-	"google.golang.org/protobuf/encoding/protojson"
+	"go.opentelemetry.io/otel/propagation"
 
-	"encoding/json"
+	"bytes"
 	"fmt"
 	"io"
 	"net/url"
-	"strings"
 
 	"github.com/go-kratos/kratos/v2/middleware/recovery"
 	"github.com/go-kratos/kratos/v2/middleware/tracing"
@@ -31,38 +26,8 @@ const CtxCorrelationIdKey = CtxKey("x-correlation-id")
 const CtxSystemPeerKey = CtxKey("x-system-peer")
 const CtxSignedHeadersKey = CtxKey("x-signed-headers")
 const CtxAuthorizationKey = CtxKey("Authorization")
-
-func getCorrelationIdFromCtx(ctx context.Context) string {
-	correlationId := ctx.Value(CtxCorrelationIdKey)
-	if correlationId == nil {
-		return uuid.NewString()
-	}
-	return correlationId.(string)
-}
-
-func ClientCorrelationIdInjector() middleware.Middleware {
-
-	return func(handler middleware.Handler) middleware.Handler {
-		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
-			if tr, ok := transport.FromClientContext(ctx); ok {
-				tr.RequestHeader().Set(string(CtxCorrelationIdKey), getCorrelationIdFromCtx(ctx))
-			}
-			return handler(ctx, req)
-		}
-	}
-}
-
-func ServerCorrelationIdInjector() middleware.Middleware {
-	return func(handler middleware.Handler) middleware.Handler {
-		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
-			if tr, ok := transport.FromServerContext(ctx); ok {
-				correlationId := tr.RequestHeader().Get(string(CtxCorrelationIdKey))
-				ctx = transport.NewServerContext(context.WithValue(ctx, CtxCorrelationIdKey, correlationId), tr)
-			}
-			return handler(ctx, req)
-		}
-	}
-}
+const CtxUserIdKey = CtxKey("user-id")
+const CtxChannelKey = CtxKey("channel")
 
 func ServerSecurityHeaderValidator() middleware.Middleware {
 	return func(handler middleware.Handler) middleware.Handler {
@@ -79,21 +44,49 @@ func ServerSecurityHeaderValidator() middleware.Middleware {
 	}
 }
 
-func NewHttpClient(ctx context.Context, endpoint string, logger log.Logger) (*khttp.Client, error) {
-	kjson.MarshalOptions = protojson.MarshalOptions{
-		UseProtoNames: true,
-	}
-	t := http.DefaultTransport.(*http.Transport).Clone()
-	t.MaxIdleConns = 100
-	t.MaxConnsPerHost = 200
-	t.MaxIdleConnsPerHost = 100
-	b3Propagator := b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader | b3.B3SingleHeader))
-	httpClient, err := khttp.NewClient(ctx, khttp.WithEndpoint(endpoint), khttp.WithMiddleware(
+// clientMiddleware is the middleware stack shared by the HTTP and gRPC clients, forwarding the
+// correlation id and the caller identity extracted by the server stack
+func clientMiddleware(logger log.Logger, propagator propagation.TextMapPropagator, identityOpts []IdentityOption) []middleware.Middleware {
+	return []middleware.Middleware{
 		recovery.Recovery(),
-		tracing.Client(tracing.WithPropagator(b3Propagator)),
+		tracing.Client(tracing.WithPropagator(propagator)),
 		ClientCorrelationIdInjector(),
+		ClientIdentityInjector(identityOpts...),
 		Client(logger),
-	), khttp.WithTimeout(time.Second*10), khttp.WithTransport(t))
+	}
+}
+
+// NewHttpClient returns an HTTP client for endpoint with the standard client middleware stack.
+// endpoint is either a host or a "discovery:///<service>" target resolved by WithHttpDiscovery.
+func NewHttpClient(ctx context.Context, endpoint string, logger log.Logger, opts ...ClientOption) (*khttp.Client, error) {
+	o := newClientOptions(opts)
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = o.maxIdleConns
+	t.MaxConnsPerHost = o.maxConnsPerHost
+	t.MaxIdleConnsPerHost = o.maxIdleConnsPerHost
+	t.TLSClientConfig = o.tlsConf
+	var resolver *nodeResolver
+	if service, ok := discoveryService(endpoint); ok && o.discovery != nil {
+		var err error
+		if resolver, err = newNodeResolver(ctx, service, o, logger); err != nil {
+			log.With(logger).Log(log.LevelError, "failed to resolve service instances", err)
+			return nil, err
+		}
+	}
+	clientOpts := []khttp.ClientOption{
+		khttp.WithEndpoint(endpoint),
+		khttp.WithMiddleware(append(clientMiddleware(logger, o.propagator, o.identityOpts), o.middleware...)...),
+		// the timeout is applied by the transport so streams can outlive it
+		khttp.WithTimeout(0),
+		khttp.WithTransport(newClientTransport(t, o, resolver)),
+		khttp.WithRequestEncoder(o.codec.requestEncoder),
+		khttp.WithResponseDecoder(o.codec.responseDecoder),
+		khttp.WithErrorDecoder(DecodeHttpError),
+	}
+	if o.tlsConf != nil {
+		clientOpts = append(clientOpts, khttp.WithTLSConfig(o.tlsConf))
+	}
+	httpClient, err := khttp.NewClient(ctx, clientOpts...)
 
 	if err != nil {
 		log.With(logger).Log(log.LevelError, "failed to initialize http client", err)
@@ -102,68 +95,148 @@ func NewHttpClient(ctx context.Context, endpoint string, logger log.Logger) (*kh
 	return httpClient, nil
 }
 
-func DoPost[T any](ctx context.Context, hc *khttp.Client, url string, headers map[string]string, body io.Reader, responseType T) (T, error) {
-	return MakeHTTPRequest(ctx, hc, url, http.MethodPost, headers, make(map[string][]string, 0), body, responseType)
+func DoPost[T any](ctx context.Context, hc *khttp.Client, url string, headers map[string]string, body io.Reader, responseType T, opts ...RequestOption) (T, error) {
+	return MakeHTTPRequest(ctx, hc, url, http.MethodPost, headers, make(map[string][]string, 0), body, responseType, opts...)
 }
 
-// in the case of GET, the parameter queryParameters is transferred to the URL as query parameters
-// in the case of POST, the parameter body, an io.Reader, is used
-func MakeHTTPRequest[T any](ctx context.Context, hc *khttp.Client, fullUrl string, httpMethod string, headers map[string]string, queryParameters url.Values, body io.Reader, responseType T) (T, error) {
-	u, err := url.Parse(fullUrl)
+func DoGet[T any](ctx context.Context, hc *khttp.Client, url string, headers map[string]string, responseType T, opts ...RequestOption) (T, error) {
+	return MakeHTTPRequest(ctx, hc, url, http.MethodGet, headers, make(map[string][]string, 0), nil, responseType, opts...)
+}
+
+func DoPut[R any, T any](ctx context.Context, hc *khttp.Client, url string, headers map[string]string, request R, responseType T, opts ...RequestOption) (T, error) {
+	return DoRequest(ctx, hc, http.MethodPut, url, headers, request, responseType, opts...)
+}
+
+func DoPatch[R any, T any](ctx context.Context, hc *khttp.Client, url string, headers map[string]string, request R, responseType T, opts ...RequestOption) (T, error) {
+	return DoRequest(ctx, hc, http.MethodPatch, url, headers, request, responseType, opts...)
+}
+
+func DoDelete[T any](ctx context.Context, hc *khttp.Client, url string, headers map[string]string, responseType T, opts ...RequestOption) (T, error) {
+	return MakeHTTPRequest(ctx, hc, url, http.MethodDelete, headers, make(map[string][]string, 0), nil, responseType, opts...)
+}
+
+// DoRequest marshals request with the codec of the Content-Type header, application/json by
+// default, using the protojson options of the client, or WithRequestCodec, for proto messages,
+// and decodes the response.
+func DoRequest[R any, T any](ctx context.Context, hc *khttp.Client, httpMethod string, url string, headers map[string]string, request R, responseType T, opts ...RequestOption) (T, error) {
+	contentType := headers["Content-Type"]
+	if contentType == "" {
+		contentType = "application/json"
+		headers = withHeader(headers, "Content-Type", contentType)
+	}
+	o := newRequestOptions(opts)
+	data, err := o.codec.codecFor(contentType).Marshal(request)
 	if err != nil {
 		return responseType, err
 	}
+	rc := &requestCodec{value: request, contentType: contentType, encode: true}
+	return makeHTTPRequest(ctx, hc, url, httpMethod, headers, make(map[string][]string, 0), bytes.NewReader(data), responseType, o, rc)
+}
 
-	// if it's a GET, we need to append the query parameters.
-	if httpMethod == "GET" {
-		q := u.Query()
+// withHeader returns a copy of headers with key set to value
+func withHeader(headers map[string]string, key, value string) map[string]string {
+	copied := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
 
-		for k, v := range queryParameters {
-			// this depends on the type of api, you may need to do it for each of v
-			q.Set(k, strings.Join(v, ","))
-		}
-		// set the query to the encoded parameters
-		u.RawQuery = q.Encode()
+// MakeHTTPRequest sends the request and decodes the response with the codec of its content type,
+// using the protojson options of the client, or WithRequestCodec, for json. The {name}
+// placeholders of fullUrl are expanded with WithPathParams and queryParameters are added to
+// the URL whatever the method, as well as those of WithQueryParams. body is sent as is.
+func MakeHTTPRequest[T any](ctx context.Context, hc *khttp.Client, fullUrl string, httpMethod string, headers map[string]string, queryParameters url.Values, body io.Reader, responseType T, opts ...RequestOption) (T, error) {
+	return makeHTTPRequest(ctx, hc, fullUrl, httpMethod, headers, queryParameters, body, responseType, newRequestOptions(opts), &requestCodec{})
+}
+
+func makeHTTPRequest[T any](ctx context.Context, hc *khttp.Client, fullUrl string, httpMethod string, headers map[string]string, queryParameters url.Values, body io.Reader, responseType T, o *requestOptions, rc *requestCodec) (T, error) {
+	if !o.codecOverride {
+		ctx = context.WithValue(ctx, codecKey{}, rc)
+	}
+	res, err := sendHTTPRequest(ctx, hc, fullUrl, httpMethod, headers, queryParameters, body, o)
+	if err != nil {
+		return responseType, err
 	}
+	defer res.Body.Close()
 
-	// regardless of GET or POST, we can safely add the body
-	req, err := http.NewRequestWithContext(ctx, httpMethod, u.String(), body)
+	responseData, err := readLimited(res.Body, o.maxResponseSize)
 	if err != nil {
 		return responseType, err
 	}
 
-	// for each header passed, add the header value to the request
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	var responseObject T
+	if len(responseData) == 0 {
+		// e.g. 204 No Content
+		return responseObject, nil
+	}
+	codec := o.codec
+	if rc.client != nil {
+		codec = *rc.client
 	}
+	responseObject, err = unmarshalInto[T](codec.codecFor(res.Header.Get("Content-Type")), responseData)
 
-	// finally, do the request
-	res, err := hc.Do(req)
 	if err != nil {
 		return responseType, err
 	}
 
-	if res == nil {
-		return responseType, fmt.Errorf("error: calling %s returned empty response", u.String())
-	}
+	return responseObject, nil
+}
 
-	responseData, err := io.ReadAll(res.Body)
+// MakeHTTPStream sends the request like MakeHTTPRequest and hands over the response body
+// without reading it, e.g. for DecodeNDJSON or DecodeSSE. The caller must close it.
+// WithMaxResponseSize fails the read once the body exceeds the limit. With NewHttpClient the
+// client timeout only bounds the wait for the response headers so the stream can last, ctx
+// bounds the whole stream. Other clients apply their http.Client timeout to the body too.
+func MakeHTTPStream(ctx context.Context, hc *khttp.Client, fullUrl string, httpMethod string, headers map[string]string, queryParameters url.Values, body io.Reader, opts ...RequestOption) (io.ReadCloser, error) {
+	o := newRequestOptions(opts)
+	res, err := sendHTTPRequest(withStreaming(ctx), hc, fullUrl, httpMethod, headers, queryParameters, body, o)
 	if err != nil {
-		return responseType, err
+		return nil, err
+	}
+	if o.maxResponseSize > 0 {
+		return &limitedReadCloser{ReadCloser: res.Body, limit: o.maxResponseSize, remaining: o.maxResponseSize}, nil
 	}
+	return res.Body, nil
+}
 
-	defer res.Body.Close()
+// sendHTTPRequest sends the request and returns the 2xx response, whose body must be closed
+func sendHTTPRequest(ctx context.Context, hc *khttp.Client, fullUrl string, httpMethod string, headers map[string]string, queryParameters url.Values, body io.Reader, o *requestOptions) (*http.Response, error) {
+	fullUrl, err := expandPath(fullUrl, o.pathParams)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(fullUrl)
+	if err != nil {
+		return nil, err
+	}
+	encodeQuery(u, queryParameters, o.queryEncoding)
+	encodeQuery(u, o.queryParams, o.queryEncoding)
 
-	if res.StatusCode != http.StatusOK {
-		return responseType, fmt.Errorf("error calling %s:\nstatus: %s\nresponseData: %s", u.String(), res.Status, responseData)
+	req, err := http.NewRequestWithContext(ctx, httpMethod, u.String(), body)
+	if err != nil {
+		return nil, err
 	}
 
-	var responseObject T
-	err = json.Unmarshal(responseData, &responseObject)
+	// for each header passed, add the header value to the request
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 
+	// finally, do the request
+	res, err := hc.Do(req)
 	if err != nil {
-		return responseType, err
+		return nil, err
 	}
 
-	return responseObject, nil
+	if res == nil {
+		return nil, fmt.Errorf("error: calling %s returned empty response", u.String())
+	}
+
+	// clients without DecodeHttpError as error decoder may return any status
+	if err := DecodeHttpError(ctx, res); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
@@ -0,0 +1,56 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "github.com/achuala/kratos-extn/api/gen"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestTypedHelpers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	request := &pb.SensitiveTestData{Name: "name", Secret: "secret"}
+
+	tests := []struct {
+		name        string
+		contentType string
+	}{
+		{"json", ""},
+		{"protobuf", "application/x-protobuf"},
+		{"form", "application/x-www-form-urlencoded"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if test.contentType != "" {
+				headers["Content-Type"] = test.contentType
+			}
+			reply, err := DoPut(ctx, hc, srv.URL, headers, request, &pb.SensitiveTestData{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reply.GetName() != "name" || reply.GetSecret() != "secret" {
+				t.Fatalf("unexpected reply %v", reply)
+			}
+		})
+	}
+
+	raw, err := DoPatch(ctx, hc, srv.URL, nil, map[string]int{"count": 1}, map[string]int{})
+	if err != nil || raw["count"] != 1 {
+		t.Fatalf("unexpected reply %v %v", raw, err)
+	}
+}
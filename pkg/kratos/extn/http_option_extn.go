@@ -0,0 +1,214 @@
+package extn
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ClientOption is a NewHttpClient option.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	timeout             time.Duration
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	tlsConf             *tls.Config
+	middleware          []middleware.Middleware
+	propagator          propagation.TextMapPropagator
+	userAgent           string
+	codec               jsonCodec
+	retry               *RetryPolicy
+	breaker             *breakerGroup
+	limiter             *limiterGroup
+	idempotencyKey      func() string
+	cache               *cacheTransport
+	hedge               *HedgePolicy
+	discovery           registry.Discovery
+	balancer            selector.Builder
+	nodeFilters         []selector.NodeFilter
+	block               bool
+	watchCtx            context.Context
+	identityOpts        []IdentityOption
+}
+
+func newClientOptions(opts []ClientOption) *clientOptions {
+	o := &clientOptions{
+		timeout:             time.Second * 10,
+		maxIdleConns:        100,
+		maxIdleConnsPerHost: 100,
+		maxConnsPerHost:     200,
+		propagator:          defaultPropagator,
+		balancer:            defaultBalancer,
+		watchCtx:            context.Background(),
+		codec:               defaultJsonCodec(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithHttpTimeout sets the request timeout, 10s by default. It covers reading the response
+// body, except for MakeHTTPStream where it only bounds the wait for the response headers.
+func WithHttpTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithHttpPool sets the connection pool sizes, by default 100 idle connections in total and
+// per host, and 200 connections per host.
+func WithHttpPool(maxIdleConns, maxIdleConnsPerHost, maxConnsPerHost int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxIdleConns = maxIdleConns
+		o.maxIdleConnsPerHost = maxIdleConnsPerHost
+		o.maxConnsPerHost = maxConnsPerHost
+	}
+}
+
+// WithHttpTLSConfig enables TLS with conf.
+func WithHttpTLSConfig(conf *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsConf = conf
+	}
+}
+
+// WithHttpMiddleware appends middleware after the standard client stack.
+func WithHttpMiddleware(m ...middleware.Middleware) ClientOption {
+	return func(o *clientOptions) {
+		o.middleware = append(o.middleware, m...)
+	}
+}
+
+// WithHttpPropagator sets the trace propagator, B3 single and multiple headers by default.
+func WithHttpPropagator(propagator propagation.TextMapPropagator) ClientOption {
+	return func(o *clientOptions) {
+		o.propagator = propagator
+	}
+}
+
+// WithHttpIdentity sets the ClientIdentityInjector options, to match WithServerIdentity.
+func WithHttpIdentity(opts ...IdentityOption) ClientOption {
+	return func(o *clientOptions) {
+		o.identityOpts = opts
+	}
+}
+
+// WithHttpUserAgent sets the User-Agent header of the requests.
+func WithHttpUserAgent(userAgent string) ClientOption {
+	return func(o *clientOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithHttpCodec sets the protojson options of the client json codec used by Invoke and the
+// request helpers such as DoPut, by default proto field names are used and unknown fields are
+// discarded. WithRequestCodec overrides them for one request.
+func WithHttpCodec(marshal protojson.MarshalOptions, unmarshal protojson.UnmarshalOptions) ClientOption {
+	return func(o *clientOptions) {
+		o.codec = jsonCodec{marshalOptions: marshal, unmarshalOptions: unmarshal}
+	}
+}
+
+// userAgentTransport sets the User-Agent of requests not carrying one
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// timeoutTransport bounds requests by a timeout, replacing http.Client.Timeout which can't be
+// lifted for streamed responses
+type timeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isStreaming(req.Context()) {
+		ctx, cancel := context.WithCancel(req.Context())
+		timer := time.AfterFunc(t.timeout, cancel)
+		res, err := t.next.RoundTrip(req.WithContext(ctx))
+		if !timer.Stop() && err != nil {
+			err = fmt.Errorf("%w: no response headers within %s", context.DeadlineExceeded, t.timeout)
+		}
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		res.Body = &releaseOnClose{ReadCloser: res.Body, release: cancel}
+		return res, nil
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &releaseOnClose{ReadCloser: res.Body, release: cancel}
+	return res, nil
+}
+
+// newClientTransport wraps the base transport with the round trippers enabled in o, so they
+// apply to both Invoke and Do requests. resolver is nil without discovery.
+func newClientTransport(base http.RoundTripper, o *clientOptions, resolver *nodeResolver) http.RoundTripper {
+	rt := base
+	if o.limiter != nil {
+		rt = &limiterTransport{next: rt, group: o.limiter}
+	}
+	if o.breaker != nil {
+		rt = &breakerTransport{next: rt, group: o.breaker}
+	}
+	if o.hedge != nil {
+		// inside retry, every hedged request goes through the breaker of its host and the
+		// limiter of the downstream
+		hedge := newHedgeTransport(rt, *o.hedge)
+		if resolver != nil && len(o.hedge.Hosts) == 0 {
+			hedge.hosts = resolver.hosts
+		}
+		rt = hedge
+	}
+	if resolver != nil {
+		// inside retry so every attempt picks an instance
+		rt = &balancerTransport{next: rt, resolver: resolver}
+	}
+	if o.retry != nil {
+		rt = &retryTransport{next: rt, policy: *o.retry}
+	}
+	if o.cache != nil {
+		// outside retry so a hit skips the downstream entirely
+		cache := *o.cache
+		cache.next = rt
+		rt = &cache
+	}
+	if o.idempotencyKey != nil {
+		// outside retry so every attempt carries the same key
+		rt = &idempotencyKeyTransport{next: rt, generator: o.idempotencyKey}
+	}
+	// outside retry so the body encoded with the client codec is the one resent
+	rt = &codecTransport{next: rt, codec: o.codec}
+	if o.userAgent != "" {
+		rt = &userAgentTransport{next: rt, userAgent: o.userAgent}
+	}
+	if o.timeout > 0 {
+		rt = &timeoutTransport{next: rt, timeout: o.timeout}
+	}
+	return rt
+}
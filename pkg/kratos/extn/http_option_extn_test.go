@@ -0,0 +1,45 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/achuala/kratos-extn/api/gen"
+	kjson "github.com/go-kratos/kratos/v2/encoding/json"
+	"github.com/go-kratos/kratos/v2/log"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestNewHttpClientOptions(t *testing.T) {
+	var userAgent, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"reply","unknown":1}`))
+	}))
+	defer srv.Close()
+
+	globalOptions := kjson.MarshalOptions
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger,
+		WithHttpUserAgent("extn-test"),
+		WithHttpCodec(protojson.MarshalOptions{EmitUnpopulated: true}, protojson.UnmarshalOptions{DiscardUnknown: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := &pb.SensitiveTestData{}
+	if err := hc.Invoke(context.Background(), http.MethodPost, "/echo", &pb.SensitiveTestData{Name: "n"}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if userAgent != "extn-test" || !strings.Contains(body, `"secret":""`) || reply.GetName() != "reply" {
+		t.Fatalf("unexpected request %q %q or reply %v", userAgent, body, reply)
+	}
+	if kjson.MarshalOptions != globalOptions {
+		t.Fatal("expected the global kratos json options to be left untouched")
+	}
+}
@@ -0,0 +1,147 @@
+package extn
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// QueryEncoding is the encoding of multi-value query parameters.
+type QueryEncoding int
+
+const (
+	// QueryRepeat repeats the key for each value, a=1&a=2.
+	QueryRepeat QueryEncoding = iota
+	// QueryComma joins the values with commas, a=1,2.
+	QueryComma
+	// QueryBrackets repeats the key suffixed with brackets, a[]=1&a[]=2, and a[]=1 for a
+	// single value.
+	QueryBrackets
+)
+
+// RequestOption is a MakeHTTPRequest option.
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	queryEncoding   QueryEncoding
+	pathParams      map[string]string
+	queryParams     url.Values
+	maxResponseSize int64
+	codec           jsonCodec
+	// codecOverride is set by WithRequestCodec, the client codec is used otherwise
+	codecOverride bool
+}
+
+// WithQueryEncoding sets the encoding of multi-value query parameters, QueryRepeat by default.
+func WithQueryEncoding(encoding QueryEncoding) RequestOption {
+	return func(o *requestOptions) {
+		o.queryEncoding = encoding
+	}
+}
+
+// WithPathParams sets the values of the {name} placeholders of the url path, e.g. /users/{id}.
+// Values are path escaped.
+func WithPathParams(params map[string]string) RequestOption {
+	return func(o *requestOptions) {
+		o.pathParams = params
+	}
+}
+
+// WithQueryParams adds query parameters to the request, e.g. for the DoGet and DoDelete helpers.
+func WithQueryParams(params url.Values) RequestOption {
+	return func(o *requestOptions) {
+		o.queryParams = params
+	}
+}
+
+// WithMaxResponseSize fails the request with a ReasonResponseTooLarge error when the response
+// body exceeds size bytes.
+func WithMaxResponseSize(size int64) RequestOption {
+	return func(o *requestOptions) {
+		o.maxResponseSize = size
+	}
+}
+
+// WithRequestCodec overrides, for one request, the protojson options used for json request
+// and response bodies. By default those of the WithHttpCodec client option are used.
+func WithRequestCodec(marshal protojson.MarshalOptions, unmarshal protojson.UnmarshalOptions) RequestOption {
+	return func(o *requestOptions) {
+		o.codec = jsonCodec{marshalOptions: marshal, unmarshalOptions: unmarshal}
+		o.codecOverride = true
+	}
+}
+
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	o := &requestOptions{codec: defaultJsonCodec()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// expandPath replaces the {name} placeholders of the path of rawUrl with the escaped params,
+// a placeholder without param is an error, the query and fragment are left untouched
+func expandPath(rawUrl string, params map[string]string) (string, error) {
+	path, rest := rawUrl, ""
+	if i := strings.IndexAny(rawUrl, "?#"); i >= 0 {
+		path, rest = rawUrl[:i], rawUrl[i:]
+	}
+	var missing []string
+	expanded := pathParamPattern.ReplaceAllStringFunc(path, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := params[name]
+		if !ok {
+			missing = append(missing, name)
+			return placeholder
+		}
+		return url.PathEscape(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing path parameters %s for %s", strings.Join(missing, ", "), rawUrl)
+	}
+	return expanded + rest, nil
+}
+
+// encodeQuery adds params to the query of u following encoding, keys are sorted
+func encodeQuery(u *url.URL, params url.Values, encoding QueryEncoding) {
+	if len(params) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString(u.RawQuery)
+	appendPair := func(k, v string) {
+		if sb.Len() > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(url.QueryEscape(k))
+		sb.WriteByte('=')
+		sb.WriteString(url.QueryEscape(v))
+	}
+	for _, k := range keys {
+		values := params[k]
+		switch {
+		case encoding == QueryComma:
+			appendPair(k, strings.Join(values, ","))
+		case encoding == QueryBrackets:
+			for _, v := range values {
+				appendPair(k+"[]", v)
+			}
+		default:
+			for _, v := range values {
+				appendPair(k, v)
+			}
+		}
+	}
+	u.RawQuery = sb.String()
+}
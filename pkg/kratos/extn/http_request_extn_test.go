@@ -0,0 +1,109 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	pb "github.com/achuala/kratos-extn/api/gen"
+	"github.com/go-kratos/kratos/v2/log"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestMakeHTTPRequestUrl(t *testing.T) {
+	var requestURI string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	query := url.Values{"id": {"1", "2"}, "q": {"a b"}}
+
+	tests := []struct {
+		name     string
+		opts     []RequestOption
+		expected string
+	}{
+		{"repeat", nil, "/users/a%2Fb?x=0&id=1&id=2&q=a+b"},
+		{"comma", []RequestOption{WithQueryEncoding(QueryComma)}, "/users/a%2Fb?x=0&id=1%2C2&q=a+b"},
+		{"brackets", []RequestOption{WithQueryEncoding(QueryBrackets)}, "/users/a%2Fb?x=0&id%5B%5D=1&id%5B%5D=2&q%5B%5D=a+b"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]RequestOption{WithPathParams(map[string]string{"id": "a/b"})}, test.opts...)
+			_, err := MakeHTTPRequest(ctx, hc, srv.URL+"/users/{id}?x=0", http.MethodDelete, nil, query, nil, struct{}{}, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requestURI != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, requestURI)
+			}
+		})
+	}
+
+	if _, err := DoGet(ctx, hc, srv.URL+"/users/{id}", nil, struct{}{}); err == nil {
+		t.Fatal("expected missing path parameter error")
+	}
+	if _, err := DoGet(ctx, hc, srv.URL+"/users/{id}", nil, struct{}{}, WithPathParams(map[string]string{"name": "n"})); err == nil {
+		t.Fatal("expected missing path parameter error")
+	}
+
+	// braces in the query are left alone, with or without placeholders in the path
+	for _, path := range []string{"/users/1", "/users/{id}"} {
+		_, err := DoGet(ctx, hc, srv.URL+path+`?filter={"a":1}`, nil, struct{}{}, WithPathParams(map[string]string{"id": "1"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(requestURI, "filter=") || strings.Contains(requestURI, "%257B") {
+			t.Fatalf("unexpected query in %s", requestURI)
+		}
+	}
+}
+
+func TestWithRequestCodec(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"reply","unknown":1}`))
+	}))
+	defer srv.Close()
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := DoPut(context.Background(), hc, srv.URL, nil, &pb.SensitiveTestData{Name: "n"}, &pb.SensitiveTestData{},
+		WithRequestCodec(protojson.MarshalOptions{EmitUnpopulated: true}, protojson.UnmarshalOptions{DiscardUnknown: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, `"secret":""`) || reply.GetName() != "reply" {
+		t.Fatalf("expected the request codec options to apply, got %q and %v", body, reply)
+	}
+
+	// the client codec applies to the request helpers, WithRequestCodec overrides it
+	hc, err = NewHttpClient(context.Background(), srv.URL, log.DefaultLogger,
+		WithHttpCodec(protojson.MarshalOptions{EmitUnpopulated: true}, protojson.UnmarshalOptions{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DoPut(context.Background(), hc, srv.URL, nil, &pb.SensitiveTestData{Name: "n"}, &pb.SensitiveTestData{})
+	if !strings.Contains(body, `"secret":""`) || err == nil {
+		t.Fatalf("expected the client codec options to apply, got %q and %v", body, err)
+	}
+	reply, err = DoPut(context.Background(), hc, srv.URL, nil, &pb.SensitiveTestData{Name: "n"}, &pb.SensitiveTestData{},
+		WithRequestCodec(protojson.MarshalOptions{}, protojson.UnmarshalOptions{DiscardUnknown: true}))
+	if err != nil || strings.Contains(body, "secret") || reply.GetName() != "reply" {
+		t.Fatalf("expected the request codec to override the client codec, got %q, %v and %v", body, reply, err)
+	}
+}
@@ -0,0 +1,151 @@
+package extn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ReasonResponseTooLarge is the error reason of responses exceeding WithMaxResponseSize.
+const ReasonResponseTooLarge = "RESPONSE_TOO_LARGE"
+
+// maxStreamLineSize bounds a single NDJSON line or SSE field
+const maxStreamLineSize = 1024 * 1024
+
+func errResponseTooLarge(limit int64) error {
+	return errors.New(http.StatusBadGateway, ReasonResponseTooLarge, "response body exceeds "+strconv.FormatInt(limit, 10)+" bytes")
+}
+
+type streamingKey struct{}
+
+// withStreaming marks the requests of ctx as streamed, the client timeout then only bounds
+// the wait for the response headers
+func withStreaming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamingKey{}, true)
+}
+
+func isStreaming(ctx context.Context) bool {
+	streaming, _ := ctx.Value(streamingKey{}).(bool)
+	return streaming
+}
+
+// readLimited reads r entirely, failing when it holds more than limit bytes, 0 means no limit
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errResponseTooLarge(limit)
+	}
+	return data, nil
+}
+
+// limitedReadCloser fails reads past the limit instead of silently truncating the body
+type limitedReadCloser struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// probe for data beyond the limit
+		var b [1]byte
+		n, err := l.ReadCloser.Read(b[:])
+		if n > 0 {
+			return 0, errResponseTooLarge(l.limit)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// DecodeNDJSON decodes each newline delimited JSON value of r into a T and passes it to fn,
+// stopping at the first error returned by fn. Proto messages are decoded with protojson.
+func DecodeNDJSON[T any](r io.Reader, fn func(T) error) error {
+	codec := jsonCodec{unmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		v, err := unmarshalInto[T](codec, line)
+		if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// SSEEvent is a server-sent event.
+type SSEEvent struct {
+	Id    string
+	Event string
+	Data  string
+	Retry int
+}
+
+// DecodeSSE parses the server-sent events of r and passes each to fn, stopping at the first
+// error returned by fn.
+func DecodeSSE(r io.Reader, fn func(SSEEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineSize)
+	var (
+		event   SSEEvent
+		data    []string
+		hasData bool
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if hasData {
+				event.Data = strings.Join(data, "\n")
+				if err := fn(event); err != nil {
+					return err
+				}
+			}
+			event, data, hasData = SSEEvent{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			event.Id = value
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "retry":
+			if retry, err := strconv.Atoi(value); err == nil {
+				event.Retry = retry
+			}
+		}
+	}
+	return scanner.Err()
+}
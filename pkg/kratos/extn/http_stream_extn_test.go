@@ -0,0 +1,112 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/achuala/kratos-extn/api/gen"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestResponseStreams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ndjson":
+			_, _ = w.Write([]byte("{\"name\":\"a\"}\n\n{\"name\":\"b\"}\n"))
+		case "/sse":
+			_, _ = w.Write([]byte(": comment\nid: 1\nevent: update\ndata: line1\ndata: line2\n\ndata: second\n\n"))
+		default:
+			_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+		}
+	}))
+	defer srv.Close()
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	_, err = MakeHTTPRequest(ctx, hc, srv.URL+"/large", http.MethodGet, nil, nil, nil, "", WithMaxResponseSize(10))
+	if errors.Reason(err) != ReasonResponseTooLarge {
+		t.Fatalf("expected response too large error, got %v", err)
+	}
+	stream, err := MakeHTTPStream(ctx, hc, srv.URL+"/large", http.MethodGet, nil, nil, nil, WithMaxResponseSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadAll(stream); errors.Reason(err) != ReasonResponseTooLarge {
+		t.Fatalf("expected response too large error, got %v", err)
+	}
+	stream.Close()
+
+	stream, err = MakeHTTPStream(ctx, hc, srv.URL+"/ndjson", http.MethodGet, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	err = DecodeNDJSON(stream, func(m *pb.SensitiveTestData) error {
+		names = append(names, m.GetName())
+		return nil
+	})
+	stream.Close()
+	if err != nil || strings.Join(names, ",") != "a,b" {
+		t.Fatalf("unexpected ndjson values %v %v", names, err)
+	}
+
+	stream, err = MakeHTTPStream(ctx, hc, srv.URL+"/sse", http.MethodGet, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []SSEEvent
+	err = DecodeSSE(stream, func(e SSEEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	stream.Close()
+	if err != nil || len(events) != 2 || events[0].Id != "1" || events[0].Event != "update" || events[0].Data != "line1\nline2" || events[1].Data != "second" {
+		t.Fatalf("unexpected events %+v %v", events, err)
+	}
+}
+
+func TestStreamOutlivesClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("{\"name\":\"tick\"}\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Millisecond * 100)
+		}
+	}))
+	defer srv.Close()
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger, WithHttpTimeout(time.Millisecond*50))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := MakeHTTPStream(context.Background(), hc, srv.URL, http.MethodGet, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var ticks int
+	err = DecodeNDJSON(stream, func(*pb.SensitiveTestData) error {
+		ticks++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected the stream to outlive the client timeout, got %v after %d records", err, ticks)
+	}
+	if ticks != 3 {
+		t.Fatalf("expected 3 records, got %d", ticks)
+	}
+
+	if _, err := DoGet(context.Background(), hc, srv.URL, nil, struct{}{}); err == nil {
+		t.Fatal("expected the client timeout to bound regular requests")
+	}
+}
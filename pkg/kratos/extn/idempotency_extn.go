@@ -0,0 +1,254 @@
+package extn
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// Error reasons of the Idempotency middleware.
+const (
+	ReasonIdempotencyInFlight = "IDEMPOTENCY_KEY_IN_FLIGHT"
+	ReasonIdempotencyMismatch = "IDEMPOTENCY_KEY_MISMATCH"
+)
+
+// HeaderIdempotentReplayed is set on replies replayed by the Idempotency middleware.
+const HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+// ErrIdempotencyInFlight is returned by IdempotencyStore.Begin while the first request
+// with the same key is still being handled.
+var ErrIdempotencyInFlight = errors.Conflict(ReasonIdempotencyInFlight, "a request with the same idempotency key is in flight")
+
+// IdempotencyRecord is the outcome of the first request made with an idempotency key.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request payload, a key reused with another payload is rejected.
+	Fingerprint string
+	Reply       interface{}
+	Err         error
+	// Header holds the reply headers set by the handler, restored on replays.
+	Header map[string][]string
+}
+
+// IdempotencyStore keeps the outcome of requests per idempotency key.
+type IdempotencyStore interface {
+	// Begin reserves key and returns nil, or returns the completed record of key, or
+	// ErrIdempotencyInFlight when key is reserved but not completed.
+	Begin(ctx context.Context, key string) (*IdempotencyRecord, error)
+	// Complete stores the record of a reserved key.
+	Complete(ctx context.Context, key string, record *IdempotencyRecord) error
+	// Release drops the reservation of key so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyOption is an Idempotency middleware option.
+type IdempotencyOption func(*idempotencyOptions)
+
+type idempotencyOptions struct {
+	scope func(ctx context.Context, header transport.Header) string
+}
+
+// WithIdempotencyScope sets the function returning the caller scope of idempotency keys, so
+// callers reusing the same key never see each other's replies. By default the scope is the
+// full Authorization credential and the RequestIdentity of the context.
+func WithIdempotencyScope(scope func(ctx context.Context, header transport.Header) string) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.scope = scope
+	}
+}
+
+// defaultIdempotencyScope scopes keys by the Authorization credential and the caller identity,
+// not by the unverified token subject which a forged token could reuse
+func defaultIdempotencyScope(ctx context.Context, header transport.Header) string {
+	scope := header.Get(string(CtxAuthorizationKey))
+	if identity, ok := IdentityFromContext(ctx); ok {
+		scope += "|" + identity.SystemPeer + "|" + identity.Channel + "|" + identity.UserId
+	}
+	return scope
+}
+
+// Idempotency is a server middleware replaying the first outcome of requests carrying an
+// Idempotency-Key header for duplicates with the same key, operation and caller scope.
+// Duplicates arriving while the first request is in flight are rejected with a conflict
+// error. Server errors (5xx) and panics are not stored so the request can be retried.
+func Idempotency(store IdempotencyStore, opts ...IdempotencyOption) middleware.Middleware {
+	o := &idempotencyOptions{scope: defaultIdempotencyScope}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			idempotencyKey := tr.RequestHeader().Get(HeaderIdempotencyKey)
+			if idempotencyKey == "" {
+				return handler(ctx, req)
+			}
+			scope := sha256.Sum256([]byte(o.scope(ctx, tr.RequestHeader())))
+			key := tr.Operation() + ":" + hex.EncodeToString(scope[:8]) + ":" + idempotencyKey
+			fingerprint := requestFingerprint(req)
+			record, err := store.Begin(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if record != nil {
+				if record.Fingerprint != fingerprint {
+					return nil, errors.New(http.StatusUnprocessableEntity, ReasonIdempotencyMismatch, "idempotency key reused with a different request")
+				}
+				for name, values := range record.Header {
+					for i, v := range values {
+						if i == 0 {
+							tr.ReplyHeader().Set(name, v)
+						} else {
+							tr.ReplyHeader().Add(name, v)
+						}
+					}
+				}
+				tr.ReplyHeader().Set(HeaderIdempotentReplayed, "true")
+				return record.Reply, record.Err
+			}
+			before := headerValues(tr.ReplyHeader())
+			completed := false
+			defer func() {
+				// release the key when the handler panics, the recovery middleware runs outside
+				if !completed {
+					_ = store.Release(ctx, key)
+				}
+			}()
+			reply, err = handler(ctx, req)
+			if err != nil && errors.FromError(err).Code >= http.StatusInternalServerError {
+				return reply, err
+			}
+			completed = true
+			header := headerValues(tr.ReplyHeader())
+			for name, values := range header {
+				if slices.Equal(before[name], values) {
+					delete(header, name)
+				}
+			}
+			_ = store.Complete(ctx, key, &IdempotencyRecord{Fingerprint: fingerprint, Reply: reply, Err: err, Header: header})
+			return reply, err
+		}
+	}
+}
+
+// headerValues copies the values of h
+func headerValues(h transport.Header) map[string][]string {
+	values := make(map[string][]string)
+	for _, name := range h.Keys() {
+		values[name] = slices.Clone(h.Values(name))
+	}
+	return values
+}
+
+// requestFingerprint hashes the request payload
+func requestFingerprint(req interface{}) string {
+	var data []byte
+	if msg, ok := req.(proto.Message); ok {
+		data, _ = proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	} else {
+		data = []byte(extractArgs(req))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type memoryIdempotencyEntry struct {
+	record  *IdempotencyRecord
+	expires time.Time
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore whose keys expire after a TTL.
+type MemoryIdempotencyStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	entries   map[string]*memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+// NewMemoryIdempotencyStore returns a store keeping keys, reserved or completed, for ttl.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ttl: ttl, entries: make(map[string]*memoryIdempotencyEntry), lastSweep: time.Now()}
+}
+
+func (s *MemoryIdempotencyStore) Begin(_ context.Context, key string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		if entry.record == nil {
+			return nil, ErrIdempotencyInFlight
+		}
+		return entry.record, nil
+	}
+	s.entries[key] = &memoryIdempotencyEntry{expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if msg, ok := record.Reply.(proto.Message); ok {
+		// keep the reply safe from later mutations by the caller
+		record = &IdempotencyRecord{Fingerprint: record.Fingerprint, Reply: proto.Clone(msg), Err: record.Err, Header: record.Header}
+	}
+	s.entries[key] = &memoryIdempotencyEntry{record: record, expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops the expired entries, at most once per ttl
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// WithHttpIdempotencyKey adds an Idempotency-Key header to POST and PATCH requests without
+// one, so they are retried with the same key. generator defaults to random UUIDs, a
+// CorrelationIdGenerator such as SnowflakeGenerator can be used.
+func WithHttpIdempotencyKey(generator func() string) ClientOption {
+	if generator == nil {
+		generator = uuid.NewString
+	}
+	return func(o *clientOptions) {
+		o.idempotencyKey = generator
+	}
+}
+
+type idempotencyKeyTransport struct {
+	next      http.RoundTripper
+	generator func() string
+}
+
+func (t *idempotencyKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if (req.Method == http.MethodPost || req.Method == http.MethodPatch) && req.Header.Get(HeaderIdempotencyKey) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(HeaderIdempotencyKey, t.generator())
+	}
+	return t.next.RoundTrip(req)
+}
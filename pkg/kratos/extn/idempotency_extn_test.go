@@ -0,0 +1,198 @@
+package extn
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/achuala/kratos-extn/api/gen"
+	"github.com/achuala/kratos-extn/pkg/snowflake"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestIdempotency(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute)
+	var calls int
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := Idempotency(store)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		close(started)
+		<-release
+		return &pb.AuditTestTarget{Id: "created"}, nil
+	})
+	call := func(key, id string) (interface{}, error, *testTransport) {
+		tr := newTestTransport("/options.AuditTestService/Update")
+		tr.reqHeader.Set(HeaderIdempotencyKey, key)
+		reply, err := handler(transport.NewServerContext(context.Background(), tr), &pb.AuditTestRequest{Target: &pb.AuditTestTarget{Id: id}})
+		return reply, err, tr
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = call("key-1", "acc-1")
+	}()
+	<-started
+	if _, err, _ := call("key-1", "acc-1"); errors.Reason(err) != ReasonIdempotencyInFlight {
+		t.Fatalf("expected in-flight duplicate to be rejected, got %v", err)
+	}
+	close(release)
+	<-done
+
+	reply, err, tr := call("key-1", "acc-1")
+	if err != nil || reply.(*pb.AuditTestTarget).GetId() != "created" || tr.rpyHeader.Get(HeaderIdempotentReplayed) != "true" {
+		t.Fatalf("expected first reply to be replayed, got %v %v", reply, err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected handler to run once, got %d", calls)
+	}
+	if _, err, _ := call("key-1", "acc-2"); errors.Reason(err) != ReasonIdempotencyMismatch {
+		t.Fatalf("expected key reused with another request to be rejected, got %v", err)
+	}
+}
+
+func TestIdempotencyReleasesServerErrors(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute)
+	var calls int
+	handler := Idempotency(store)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if calls++; calls == 1 {
+			return nil, errors.ServiceUnavailable("DB_BUSY", "busy")
+		}
+		return nil, nil
+	})
+	tr := newTestTransport("/options.AuditTestService/Update")
+	tr.reqHeader.Set(HeaderIdempotencyKey, "key-1")
+	ctx := transport.NewServerContext(context.Background(), tr)
+	if _, err := handler(ctx, &pb.AuditTestRequest{}); err == nil {
+		t.Fatal("expected first call to fail")
+	}
+	if _, err := handler(ctx, &pb.AuditTestRequest{}); err != nil || calls != 2 {
+		t.Fatalf("expected retry after a server error to run the handler, got %v after %d calls", err, calls)
+	}
+}
+
+func TestHttpIdempotencyKey(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(HeaderIdempotencyKey))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger,
+		WithHttpRetry(policy), WithHttpIdempotencyKey(SnowflakeGenerator(snowflake.New(1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	res, err := hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("expected the generated key to be reused across retries, got %q", keys)
+	}
+
+	keys = nil
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	res, err = hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if keys[len(keys)-1] != "" {
+		t.Fatal("expected no key on idempotent methods")
+	}
+}
+
+func TestIdempotencyReleasesPanics(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute)
+	var calls int
+	handler := Idempotency(store)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if calls++; calls == 1 {
+			panic("boom")
+		}
+		return nil, nil
+	})
+	tr := newTestTransport("/options.AuditTestService/Update")
+	tr.reqHeader.Set(HeaderIdempotencyKey, "key-1")
+	ctx := transport.NewServerContext(context.Background(), tr)
+	func() {
+		defer func() { _ = recover() }()
+		_, _ = handler(ctx, &pb.AuditTestRequest{})
+	}()
+	if _, err := handler(ctx, &pb.AuditTestRequest{}); err != nil || calls != 2 {
+		t.Fatalf("expected retry after a panic to run the handler, got %v after %d calls", err, calls)
+	}
+}
+
+func TestIdempotencyCallerScope(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute)
+	handler := Idempotency(store)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		identity, _ := IdentityFromContext(ctx)
+		return &pb.AuditTestTarget{Id: identity.UserId}, nil
+	})
+	call := func(authorization, userId string) string {
+		tr := newTestTransport("/options.AuditTestService/Update")
+		tr.reqHeader.Set(HeaderIdempotencyKey, "key-1")
+		tr.reqHeader.Set(string(CtxAuthorizationKey), authorization)
+		ctx := NewIdentityContext(transport.NewServerContext(context.Background(), tr), &RequestIdentity{UserId: userId})
+		reply, err := handler(ctx, &pb.AuditTestRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reply.(*pb.AuditTestTarget).GetId()
+	}
+	if call("Basic YWxpY2U6cGFzcw==", "alice") != "alice" || call("Basic Ym9iOnBhc3M=", "bob") != "bob" {
+		t.Fatal("expected callers reusing a key not to share replies")
+	}
+	if call("Basic YWxpY2U6cGFzcw==", "alice") != "alice" {
+		t.Fatal("expected the reply of the same caller to be replayed")
+	}
+	// tokens with the same unverified subject are different callers
+	sub := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"carol"}`))
+	if call("Bearer e30."+sub+".valid", "carol") != "carol" || call("Bearer e30."+sub+".forged", "mallory") != "mallory" {
+		t.Fatal("expected a forged token with the same subject not to share replies")
+	}
+}
+
+func TestIdempotencyReplaysHeaders(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute)
+	handler := Idempotency(store)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		tr, _ := transport.FromServerContext(ctx)
+		tr.ReplyHeader().Set("Location", "/accounts/1")
+		return &pb.AuditTestTarget{Id: "1"}, nil
+	})
+	call := func() *testTransport {
+		tr := newTestTransport("/options.AuditTestService/Update")
+		tr.reqHeader.Set(HeaderIdempotencyKey, "key-1")
+		tr.rpyHeader.Set(HeaderRequestId, "req")
+		if _, err := handler(transport.NewServerContext(context.Background(), tr), &pb.AuditTestRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+	call()
+	tr := call()
+	if tr.rpyHeader.Get("Location") != "/accounts/1" || tr.rpyHeader.Get(HeaderIdempotentReplayed) != "true" {
+		t.Fatalf("expected the reply headers to be replayed, got %v", tr.rpyHeader)
+	}
+	for _, entry := range store.entries {
+		if len(entry.record.Header) != 1 {
+			t.Fatalf("expected only the headers set by the handler to be stored, got %v", entry.record.Header)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package extn
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type identityKey struct{}
+
+// RequestIdentity identifies the caller of a request.
+type RequestIdentity struct {
+	SystemPeer string
+	Channel    string
+	UserId     string
+}
+
+// IdentityHeaders names the headers, or gRPC metadata keys, carrying the RequestIdentity.
+type IdentityHeaders struct {
+	SystemPeer string
+	Channel    string
+	UserId     string
+}
+
+// DefaultIdentityHeaders are the headers used by the signature scheme.
+var DefaultIdentityHeaders = IdentityHeaders{
+	SystemPeer: string(CtxSystemPeerKey),
+	Channel:    string(CtxChannelKey),
+	UserId:     string(CtxUserIdKey),
+}
+
+// IdentityOption is an identity middleware option.
+type IdentityOption func(*IdentityHeaders)
+
+// WithIdentityHeaders sets the headers carrying the identity, DefaultIdentityHeaders by default.
+func WithIdentityHeaders(headers IdentityHeaders) IdentityOption {
+	return func(h *IdentityHeaders) {
+		*h = headers
+	}
+}
+
+// NewIdentityContext returns a context carrying identity.
+func NewIdentityContext(ctx context.Context, identity *RequestIdentity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the caller stored in ctx, if any.
+func IdentityFromContext(ctx context.Context) (*RequestIdentity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*RequestIdentity)
+	return identity, ok && identity != nil
+}
+
+// SystemPeerFromContext returns the calling system stored in ctx.
+func SystemPeerFromContext(ctx context.Context) string {
+	if identity, ok := IdentityFromContext(ctx); ok {
+		return identity.SystemPeer
+	}
+	return ""
+}
+
+// ChannelFromContext returns the channel of the caller stored in ctx.
+func ChannelFromContext(ctx context.Context) string {
+	if identity, ok := IdentityFromContext(ctx); ok {
+		return identity.Channel
+	}
+	return ""
+}
+
+// UserIdFromContext returns the user id of the caller stored in ctx.
+func UserIdFromContext(ctx context.Context) string {
+	if identity, ok := IdentityFromContext(ctx); ok {
+		return identity.UserId
+	}
+	return ""
+}
+
+func newIdentityHeaders(opts []IdentityOption) IdentityHeaders {
+	headers := DefaultIdentityHeaders
+	for _, opt := range opts {
+		opt(&headers)
+	}
+	return headers
+}
+
+// ServerIdentityInjector stores the RequestIdentity read from the request headers in the context.
+func ServerIdentityInjector(opts ...IdentityOption) middleware.Middleware {
+	headers := newIdentityHeaders(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				identity := &RequestIdentity{
+					SystemPeer: tr.RequestHeader().Get(headers.SystemPeer),
+					Channel:    tr.RequestHeader().Get(headers.Channel),
+					UserId:     tr.RequestHeader().Get(headers.UserId),
+				}
+				ctx = transport.NewServerContext(NewIdentityContext(ctx, identity), tr)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// ClientIdentityInjector forwards the RequestIdentity of the context to the downstream request headers.
+func ClientIdentityInjector(opts ...IdentityOption) middleware.Middleware {
+	headers := newIdentityHeaders(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				if identity, ok := IdentityFromContext(ctx); ok {
+					setIfNotEmpty(tr.RequestHeader(), headers.SystemPeer, identity.SystemPeer)
+					setIfNotEmpty(tr.RequestHeader(), headers.Channel, identity.Channel)
+					setIfNotEmpty(tr.RequestHeader(), headers.UserId, identity.UserId)
+				}
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+func setIfNotEmpty(header transport.Header, key, value string) {
+	if value != "" {
+		header.Set(key, value)
+	}
+}
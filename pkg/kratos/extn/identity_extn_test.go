@@ -0,0 +1,32 @@
+package extn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestIdentityPropagation(t *testing.T) {
+	server := newTestTransport("/test")
+	server.reqHeader.Set("x-system-peer", "billing")
+	server.reqHeader.Set("channel", "mobile")
+	server.reqHeader.Set("user-id", "u-1")
+	client := newTestTransport("/downstream")
+
+	handler := ServerIdentityInjector()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if peer := SystemPeer()(ctx); peer != "billing" {
+			t.Fatalf("expected billing, got %v", peer)
+		}
+		call := ClientIdentityInjector()(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return call(transport.NewClientContext(DetachContext(ctx), client), req)
+	})
+	_, _ = handler(transport.NewServerContext(context.Background(), server), nil)
+	for _, key := range []string{"x-system-peer", "channel", "user-id"} {
+		if client.reqHeader.Get(key) != server.reqHeader.Get(key) {
+			t.Fatalf("expected %s to be forwarded, got %q", key, client.reqHeader.Get(key))
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// Error reasons of calls rejected by the client limiters.
+const (
+	ReasonRateLimited        = "CLIENT_RATE_LIMITED"
+	ReasonConcurrencyLimited = "CLIENT_CONCURRENCY_LIMITED"
+)
+
+// LimitPolicy limits the calls made to a downstream.
+type LimitPolicy struct {
+	// Rate is the number of calls allowed per second, 0 disables rate limiting.
+	Rate float64
+	// Burst is the number of calls allowed at once above Rate, at least 1.
+	Burst int
+	// MaxInFlight is the number of concurrent calls allowed, 0 disables concurrency limiting.
+	MaxInFlight int
+	// Wait makes calls over the limits wait, within the context deadline, instead of failing fast.
+	Wait bool
+}
+
+// LimitOption is a client limiter option.
+type LimitOption func(*limiterGroup)
+
+// WithLimit sets the policy of a downstream, the endpoint host for the HTTP client, or the
+// service name with discovery, or the operation for the ClientLimiter middleware.
+func WithLimit(key string, policy LimitPolicy) LimitOption {
+	return func(g *limiterGroup) {
+		g.policies[key] = policy
+	}
+}
+
+// WithDefaultLimit sets the policy of the downstreams without their own policy.
+func WithDefaultLimit(policy LimitPolicy) LimitOption {
+	return func(g *limiterGroup) {
+		g.fallback = &policy
+	}
+}
+
+// tokenBucket is a token bucket where tokens may be reserved ahead of time by waiting callers
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token and returns how long to wait before using it, no token is taken
+// when the wait exceeds maxWait
+func (b *tokenBucket) reserve(maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait > maxWait {
+		return 0, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// refund gives back a token reserved by a caller which stopped waiting for it
+func (b *tokenBucket) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+type limiter struct {
+	policy   LimitPolicy
+	bucket   *tokenBucket
+	inFlight chan struct{}
+}
+
+// acquire waits for, or fails fast on, the limits and returns the release function
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.bucket != nil {
+		var maxWait time.Duration
+		if l.policy.Wait {
+			maxWait = time.Duration(math.MaxInt64)
+			if deadline, ok := ctx.Deadline(); ok {
+				maxWait = time.Until(deadline)
+			}
+		}
+		wait, ok := l.bucket.reserve(maxWait)
+		if !ok {
+			return nil, errors.New(http.StatusTooManyRequests, ReasonRateLimited, "client rate limit exceeded")
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				l.bucket.refund()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+	release := func() { <-l.inFlight }
+	if !l.policy.Wait {
+		select {
+		case l.inFlight <- struct{}{}:
+			return release, nil
+		default:
+			return nil, errors.New(http.StatusTooManyRequests, ReasonConcurrencyLimited, "client concurrency limit exceeded")
+		}
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// limiterGroup holds one limiter per downstream key
+type limiterGroup struct {
+	policies map[string]LimitPolicy
+	fallback *LimitPolicy
+
+	mu       sync.Mutex
+	limiters map[string]*limiter
+}
+
+func newLimiterGroup(opts []LimitOption) *limiterGroup {
+	g := &limiterGroup{policies: make(map[string]LimitPolicy), limiters: make(map[string]*limiter)}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// get returns the limiter of key, or nil when key is not limited
+func (g *limiterGroup) get(key string) *limiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if l, ok := g.limiters[key]; ok {
+		return l
+	}
+	policy, ok := g.policies[key]
+	if !ok {
+		if g.fallback == nil {
+			g.limiters[key] = nil
+			return nil
+		}
+		policy = *g.fallback
+	}
+	l := &limiter{policy: policy}
+	if policy.Rate > 0 {
+		burst := float64(max(policy.Burst, 1))
+		l.bucket = &tokenBucket{rate: policy.Rate, burst: burst, tokens: burst, last: time.Now()}
+	}
+	if policy.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, policy.MaxInFlight)
+	}
+	g.limiters[key] = l
+	return l
+}
+
+// ClientLimiter is a client middleware limiting the calls per operation.
+func ClientLimiter(opts ...LimitOption) middleware.Middleware {
+	group := newLimiterGroup(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			tr, ok := transport.FromClientContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			l := group.get(tr.Operation())
+			if l == nil {
+				return handler(ctx, req)
+			}
+			release, err := l.acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+			return handler(ctx, req)
+		}
+	}
+}
+
+// WithHttpLimiter limits the calls of the HTTP client per downstream, the endpoint host, or
+// the service name with discovery, so the limits are shared by the instances of a service.
+func WithHttpLimiter(opts ...LimitOption) ClientOption {
+	return func(o *clientOptions) {
+		o.limiter = newLimiterGroup(opts)
+	}
+}
+
+// targetKey holds the downstream of a request whose host is replaced by an instance
+type targetKey struct{}
+
+// withTarget records target as the downstream of ctx, unless one is recorded already
+func withTarget(ctx context.Context, target string) context.Context {
+	if _, ok := ctx.Value(targetKey{}).(string); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, targetKey{}, target)
+}
+
+// requestTarget returns the downstream of req, its host unless an instance was picked for it
+func requestTarget(req *http.Request) string {
+	if target, ok := req.Context().Value(targetKey{}).(string); ok {
+		return target
+	}
+	return req.URL.Host
+}
+
+type limiterTransport struct {
+	next  http.RoundTripper
+	group *limiterGroup
+}
+
+func (t *limiterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := t.group.get(requestTarget(req))
+	if l == nil {
+		return t.next.RoundTrip(req)
+	}
+	release, err := l.acquire(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// the call is in flight until its body is consumed
+	res.Body = &releaseOnClose{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
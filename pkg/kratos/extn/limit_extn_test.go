@@ -0,0 +1,94 @@
+package extn
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestClientLimiter(t *testing.T) {
+	m := ClientLimiter(
+		WithLimit("/partner.Api/Get", LimitPolicy{Rate: 1, Burst: 1}),
+		WithLimit("/partner.Api/Wait", LimitPolicy{Rate: 50, Burst: 1, Wait: true}),
+		WithDefaultLimit(LimitPolicy{MaxInFlight: 1}),
+	)
+	handler := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if fn, ok := req.(handlerFunc); ok {
+			return fn(ctx)
+		}
+		return nil, nil
+	})
+	call := func(operation string, req interface{}) error {
+		_, err := handler(transport.NewClientContext(context.Background(), newTestTransport(operation)), req)
+		return err
+	}
+
+	if err := call("/partner.Api/Get", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("/partner.Api/Get", nil); errors.Reason(err) != ReasonRateLimited {
+		t.Fatalf("expected rate limited error, got %v", err)
+	}
+
+	start := time.Now()
+	_ = call("/partner.Api/Wait", nil)
+	if err := call("/partner.Api/Wait", nil); err != nil || time.Since(start) < time.Millisecond*10 {
+		t.Fatalf("expected the call to wait for a token, got %v", err)
+	}
+
+	var inner error
+	_ = call("/partner.Api/Other", handlerFunc(func(ctx context.Context) (interface{}, error) {
+		inner = call("/partner.Api/Other", nil)
+		return nil, nil
+	}))
+	if errors.Reason(inner) != ReasonConcurrencyLimited {
+		t.Fatalf("expected concurrency limited error, got %v", inner)
+	}
+}
+
+type handlerFunc func(ctx context.Context) (interface{}, error)
+
+func TestLimiterRefund(t *testing.T) {
+	l := newLimiterGroup([]LimitOption{WithDefaultLimit(LimitPolicy{Rate: 0.1, Burst: 1, Wait: true})}).get("downstream")
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*10, cancel)
+	if _, err := l.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait to be cancelled, got %v", err)
+	}
+	if l.bucket.tokens < -0.5 {
+		t.Fatalf("expected the reserved token to be refunded, got %f tokens", l.bucket.tokens)
+	}
+}
+
+func TestHttpLimiter(t *testing.T) {
+	a, b := newNamedServer("a"), newNamedServer("b")
+	defer a.Close()
+	defer b.Close()
+	discovery := NewStaticDiscovery()
+	discovery.SetEndpoints("orders", a.URL, b.URL)
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	hc, err := NewHttpClient(ctx, "discovery:///orders", log.DefaultLogger, WithHttpDiscovery(discovery), WithHttpBlock(),
+		WithHttpRetry(policy), WithHttpLimiter(WithLimit("orders", LimitPolicy{Rate: 0.1, Burst: 1})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = callDiscovered(t, hc, "/v1/orders")
+	// the other instance shares the quota of the service, and the rejection is not retried
+	start := time.Now()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/orders", nil)
+	if _, err := hc.Do(req); errors.Reason(err) != ReasonRateLimited || time.Since(start) > time.Millisecond*500 {
+		t.Fatalf("expected the service quota to fail fast, got %v after %s", err, time.Since(start))
+	}
+}
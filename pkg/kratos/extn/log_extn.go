@@ -11,6 +11,7 @@ import (
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -41,11 +42,15 @@ func Server(logger log.Logger) middleware.Middleware {
 				reason = se.Reason
 			}
 			level, stack := extractError(err)
+			traceId, spanId, sampled := extractTraceInfo(ctx)
 			_ = log.WithContext(ctx, logger).Log(level,
 				"kind", "server",
 				"component", kind,
 				"operation", operation,
 				"correlationId", getCorrelationIdFromCtx(ctx),
+				"traceId", traceId,
+				"spanId", spanId,
+				"sampled", sampled,
 				"request", extractArgs(req),
 				"response", extractArgs(reply),
 				"code", code,
@@ -79,11 +84,15 @@ func Client(logger log.Logger) middleware.Middleware {
 				reason = se.Reason
 			}
 			level, stack := extractError(err)
+			traceId, spanId, sampled := extractTraceInfo(ctx)
 			_ = log.WithContext(ctx, logger).Log(level,
 				"kind", "client",
 				"component", kind,
 				"operation", operation,
 				"correlationId", getCorrelationIdFromCtx(ctx),
+				"traceId", traceId,
+				"spanId", spanId,
+				"sampled", sampled,
 				"request", extractArgs(req),
 				"response", extractArgs(reply),
 				"code", code,
@@ -110,6 +119,15 @@ func extractArgs(req interface{}) string {
 	return fmt.Sprintf("%+v", req)
 }
 
+// extractTraceInfo returns the trace id, span id and sampled flag of the span in ctx
+func extractTraceInfo(ctx context.Context) (string, string, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", "", false
+	}
+	return sc.TraceID().String(), sc.SpanID().String(), sc.IsSampled()
+}
+
 // extractError returns the string of the error
 func extractError(err error) (log.Level, string) {
 	if err != nil {
@@ -1,15 +1,42 @@
 package extn
 
 import (
-	"fmt"
+	"context"
 	"testing"
 
 	pb "github.com/achuala/kratos-extn/api/gen"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestHandleSenstiveData(t *testing.T) {
 	val := &pb.SensitiveTestData{Name: "Name to be Masked", Secret: "Secret"}
-	fmt.Printf("%v\n", val)
 	handleSenstiveData(val.ProtoReflect())
-	fmt.Printf("%v", val)
+	if val.GetName() != "*************sked" {
+		t.Fatalf("expected the name to be masked but its last 4 characters, got %q", val.GetName())
+	}
+	if val.GetSecret() != "" {
+		t.Fatalf("expected the secret to be redacted, got %q", val.GetSecret())
+	}
+
+	short := &pb.SensitiveTestData{Name: "Bob"}
+	handleSenstiveData(short.ProtoReflect())
+	if short.GetName() != "****" {
+		t.Fatalf("expected a short name to be fully masked, got %q", short.GetName())
+	}
+}
+
+func TestExtractTraceInfo(t *testing.T) {
+	if traceId, spanId, sampled := extractTraceInfo(context.Background()); traceId != "" || spanId != "" || sampled {
+		t.Fatalf("expected empty trace info, got %s %s %v", traceId, spanId, sampled)
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	traceId, spanId, sampled := extractTraceInfo(ctx)
+	if traceId != sc.TraceID().String() || spanId != sc.SpanID().String() || !sampled {
+		t.Fatalf("unexpected trace info %s %s %v", traceId, spanId, sampled)
+	}
 }
@@ -0,0 +1,118 @@
+package extn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	value  io.Reader
+}
+
+// MultipartBuilder builds a multipart/form-data body from fields and files. The readers of
+// the parts are consumed once, by either Reader or Bytes.
+type MultipartBuilder struct {
+	boundary string
+	parts    []multipartPart
+}
+
+// NewMultipartBuilder returns a builder with a random boundary.
+func NewMultipartBuilder() *MultipartBuilder {
+	return &MultipartBuilder{boundary: multipart.NewWriter(io.Discard).Boundary()}
+}
+
+// SetBoundary sets the boundary, e.g. to produce a reproducible body.
+func (b *MultipartBuilder) SetBoundary(boundary string) error {
+	if err := multipart.NewWriter(io.Discard).SetBoundary(boundary); err != nil {
+		return err
+	}
+	b.boundary = boundary
+	return nil
+}
+
+// AddField adds a form field.
+func (b *MultipartBuilder) AddField(name, value string) *MultipartBuilder {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(name)))
+	return b.AddPart(header, strings.NewReader(value))
+}
+
+// AddFile adds a file read from r, contentType defaults to application/octet-stream.
+func (b *MultipartBuilder) AddFile(field, filename, contentType string, r io.Reader) *MultipartBuilder {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(field), escapeQuotes(filename)))
+	header.Set("Content-Type", contentType)
+	return b.AddPart(header, r)
+}
+
+// AddPart adds a part with its own headers, e.g. Content-Disposition and Content-Type.
+func (b *MultipartBuilder) AddPart(header textproto.MIMEHeader, r io.Reader) *MultipartBuilder {
+	b.parts = append(b.parts, multipartPart{header: header, value: r})
+	return b
+}
+
+// ContentType returns the Content-Type header of the body, including the boundary.
+func (b *MultipartBuilder) ContentType() string {
+	return "multipart/form-data; boundary=" + b.boundary
+}
+
+// Reader streams the body through a pipe, without holding the files in memory. Closing the
+// reader before the end stops the writing goroutine.
+func (b *MultipartBuilder) Reader() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(b.writeTo(pw))
+	}()
+	return pr
+}
+
+// Bytes returns the whole body, for callers needing the payload up front such as request signing.
+func (b *MultipartBuilder) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := b.writeTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (b *MultipartBuilder) writeTo(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(b.boundary); err != nil {
+		return err
+	}
+	for _, part := range b.parts {
+		pw, err := mw.CreatePart(part.header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(pw, part.value); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// DoMultipart sends the multipart body streamed from b and decodes the response like MakeHTTPRequest.
+func DoMultipart[T any](ctx context.Context, hc *khttp.Client, httpMethod string, url string, headers map[string]string, b *MultipartBuilder, responseType T, opts ...RequestOption) (T, error) {
+	headers = withHeader(headers, "Content-Type", b.ContentType())
+	body := b.Reader()
+	defer body.Close()
+	return MakeHTTPRequest(ctx, hc, url, httpMethod, headers, make(map[string][]string, 0), body, responseType, opts...)
+}
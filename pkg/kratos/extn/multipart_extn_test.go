@@ -0,0 +1,56 @@
+package extn
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestDoMultipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("document")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"` + r.FormValue("kind") + `","file":"` + header.Filename + `","type":"` +
+			header.Header.Get("Content-Type") + `","content":"` + string(content) + `"}`))
+	}))
+	defer srv.Close()
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewMultipartBuilder().
+		AddField("kind", "invoice").
+		AddFile("document", "invoice.pdf", "application/pdf", strings.NewReader("%PDF"))
+	reply, err := DoMultipart(context.Background(), hc, http.MethodPost, srv.URL, nil, b, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply["kind"] != "invoice" || reply["file"] != "invoice.pdf" || reply["type"] != "application/pdf" || reply["content"] != "%PDF" {
+		t.Fatalf("unexpected reply %v", reply)
+	}
+
+	signed := NewMultipartBuilder().AddField("kind", "invoice")
+	if err := signed.SetBoundary("fixed-boundary"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := signed.Bytes()
+	if err != nil || !bytes.Contains(data, []byte("--fixed-boundary--")) {
+		t.Fatalf("unexpected body %q %v", data, err)
+	}
+}
@@ -0,0 +1,86 @@
+package extn
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultPropagator is the trace propagator shared by the clients and message carriers.
+var defaultPropagator propagation.TextMapPropagator = b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader | b3.B3SingleHeader))
+
+// HeaderPair is a message header as exposed by Kafka style clients.
+type HeaderPair struct {
+	Key   string
+	Value []byte
+}
+
+// HeaderPairsCarrier adapts a slice of header pairs to propagation.TextMapCarrier.
+type HeaderPairsCarrier struct {
+	Headers *[]HeaderPair
+}
+
+func (c HeaderPairsCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c HeaderPairsCarrier) Set(key string, value string) {
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, HeaderPair{Key: key, Value: []byte(value)})
+}
+
+func (c HeaderPairsCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// InjectCorrelation writes the correlation id and trace context of ctx to the message
+// headers in carrier, e.g. propagation.MapCarrier or HeaderPairsCarrier.
+func InjectCorrelation(ctx context.Context, carrier propagation.TextMapCarrier) {
+	carrier.Set(string(CtxCorrelationIdKey), getCorrelationIdFromCtx(ctx))
+	defaultPropagator.Inject(ctx, carrier)
+}
+
+// ExtractCorrelation returns a context continuing the correlation id and trace context
+// found in the message headers in carrier.
+func ExtractCorrelation(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	if correlationId := carrier.Get(string(CtxCorrelationIdKey)); isValidCorrelationId(correlationId) {
+		ctx = context.WithValue(ctx, CtxCorrelationIdKey, correlationId)
+	}
+	return defaultPropagator.Extract(ctx, carrier)
+}
+
+// DetachContext returns a context that is never cancelled and only keeps the correlation
+// id, caller identity and trace context of ctx, for work outliving the request such as
+// background goroutines.
+func DetachContext(ctx context.Context) context.Context {
+	detached := context.WithValue(context.Background(), CtxCorrelationIdKey, getCorrelationIdFromCtx(ctx))
+	if identity, ok := IdentityFromContext(ctx); ok {
+		detached = NewIdentityContext(detached, identity)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		detached = trace.ContextWithSpanContext(detached, sc)
+	}
+	return detached
+}
+
+// GoDetached runs fn in a new goroutine with a context detached from ctx.
+func GoDetached(ctx context.Context, fn func(ctx context.Context)) {
+	detached := DetachContext(ctx)
+	go fn(detached)
+}
@@ -0,0 +1,34 @@
+package extn
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCorrelationCarriers(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.WithValue(context.Background(), CtxCorrelationIdKey, "corr-1"), sc)
+	carriers := map[string]propagation.TextMapCarrier{
+		"map":          propagation.MapCarrier{},
+		"header pairs": HeaderPairsCarrier{Headers: &[]HeaderPair{}},
+	}
+	for name, carrier := range carriers {
+		t.Run(name, func(t *testing.T) {
+			InjectCorrelation(DetachContext(ctx), carrier)
+			extracted := ExtractCorrelation(context.Background(), carrier)
+			if correlationId := getCorrelationIdFromCtx(extracted); correlationId != "corr-1" {
+				t.Fatalf("expected corr-1, got %s", correlationId)
+			}
+			if traceId := trace.SpanContextFromContext(extracted).TraceID(); traceId != sc.TraceID() {
+				t.Fatalf("expected trace id %s, got %s", sc.TraceID(), traceId)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package extn
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// HeaderIdempotencyKey marks a non-idempotent request as safe to resend.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// clientRejections are the error reasons of calls rejected by the client itself, they fail
+// fast rather than being retried
+var clientRejections = []string{ReasonCircuitOpen, ReasonRateLimited, ReasonConcurrencyLimited}
+
+// defaultRetryBufferSize is the MaxBufferSize of retry policies without one
+const defaultRetryBufferSize = 1 << 20
+
+// maxReasonPeekSize bounds the error body read to find the kratos error reason
+const maxReasonPeekSize = 64 * 1024
+
+// RetryPolicy configures the retries of the HTTP client.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled by Multiplier up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter is the fraction, between 0 and 1, of the backoff randomly subtracted from it.
+	Jitter float64
+	// RetryableStatus are the response status codes retried.
+	RetryableStatus []int
+	// RetryableReasons are the kratos error reasons retried whatever the status code.
+	RetryableReasons []string
+	// RetryNonIdempotent allows retrying POST and PATCH requests without an Idempotency-Key.
+	RetryNonIdempotent bool
+	// MaxBufferSize is the largest request body without GetBody buffered to be resent, 1MiB
+	// when zero, larger bodies, such as streamed uploads, are sent once without retries. A
+	// negative size disables the buffering.
+	MaxBufferSize int64
+	// MaxRetryAfter bounds the wait requested by a Retry-After header, MaxBackoff when zero.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy retries 3 times on 429, 502, 503 and 504 and on connection errors,
+// buffering request bodies up to 1MiB.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     3,
+		InitialBackoff:  time.Millisecond * 100,
+		MaxBackoff:      time.Second * 2,
+		Multiplier:      2,
+		Jitter:          0.2,
+		RetryableStatus: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		MaxBufferSize:   defaultRetryBufferSize,
+	}
+}
+
+// WithHttpRetry enables retries following policy.
+func WithHttpRetry(policy RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retry = &policy
+	}
+}
+
+// backoff returns the wait before the retry following attempt, counted from 1
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * p.Jitter * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// allows reports whether the method of req may be resent
+func (p *RetryPolicy) allows(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return p.RetryNonIdempotent || req.Header.Get(HeaderIdempotencyKey) != ""
+}
+
+// retryable reports whether res must be retried, the body is restored for the caller
+func (p *RetryPolicy) retryable(res *http.Response) bool {
+	if slices.Contains(p.RetryableStatus, res.StatusCode) {
+		return true
+	}
+	if len(p.RetryableReasons) == 0 || res.StatusCode < 400 {
+		return false
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxReasonPeekSize))
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), res.Body), res.Body}
+	if err != nil {
+		return false
+	}
+	var e struct {
+		Reason string `json:"reason"`
+	}
+	return json.Unmarshal(data, &e) == nil && slices.Contains(p.RetryableReasons, e.Reason)
+}
+
+// bufferSize returns the largest request body buffered
+func (p *RetryPolicy) bufferSize() int64 {
+	if p.MaxBufferSize == 0 {
+		return defaultRetryBufferSize
+	}
+	return max(p.MaxBufferSize, 0)
+}
+
+// retryAfterCap returns the longest Retry-After wait honoured
+func (p *RetryPolicy) retryAfterCap() time.Duration {
+	if p.MaxRetryAfter > 0 {
+		return p.MaxRetryAfter
+	}
+	return p.MaxBackoff
+}
+
+// retryAfter returns the wait requested by the Retry-After header of res, if any
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.policy.MaxAttempts <= 1 || !t.policy.allows(req) {
+		return t.next.RoundTrip(req)
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// buffer the body so it can be resent, unless it is too large
+		limit := t.policy.bufferSize()
+		data, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+		if err != nil {
+			req.Body.Close()
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		if int64(len(data)) > limit {
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(data), req.Body), req.Body}
+			return t.next.RoundTrip(req)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		res, err := t.next.RoundTrip(req)
+		if attempt >= t.policy.MaxAttempts || req.Context().Err() != nil || slices.Contains(clientRejections, errors.Reason(err)) {
+			return res, err
+		}
+		wait := t.policy.backoff(attempt)
+		if err == nil {
+			if !t.policy.retryable(res) {
+				return res, nil
+			}
+			if after, ok := retryAfter(res); ok {
+				wait = after
+				if limit := t.policy.retryAfterCap(); limit > 0 {
+					wait = min(wait, limit)
+				}
+			}
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < wait {
+			return res, err
+		}
+		if res != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxReasonPeekSize))
+			res.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
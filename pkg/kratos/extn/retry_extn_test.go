@@ -0,0 +1,147 @@
+package extn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestRetryTransport(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("attempt %d: expected body to be resent, got %q", attempts, body)
+		}
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"code":500,"reason":"DB_BUSY"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.RetryableReasons = []string{"DB_BUSY"}
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger, WithHttpRetry(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	req.Header.Set(HeaderIdempotencyKey, "key-1")
+	res, err := hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+
+	attempts = 0
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	if _, err = hc.Do(req); err == nil || attempts != 1 {
+		t.Fatalf("expected POST without idempotency key not to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestRetryTransportLimits(t *testing.T) {
+	var attempts int
+	var received []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		received, _ = io.ReadAll(r.Body)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.MaxBufferSize = 4
+	policy.MaxBackoff = time.Millisecond * 10
+	hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger, WithHttpRetry(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("streamed payload"))
+		pw.Close()
+	}()
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, pr)
+	_, _ = hc.Do(req)
+	if attempts != 1 || string(received) != "streamed payload" {
+		t.Fatalf("expected a body over the buffer size to be sent once intact, got %d attempts and %q", attempts, received)
+	}
+
+	attempts = 0
+	start := time.Now()
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	_, _ = hc.Do(req)
+	if attempts != 3 || time.Since(start) > time.Second {
+		t.Fatalf("expected Retry-After to be capped, got %d attempts in %s", attempts, time.Since(start))
+	}
+
+	// a zero buffer size is the default one, a negative one disables buffering
+	for _, test := range []struct {
+		size     int64
+		attempts int
+	}{{0, 3}, {-1, 1}} {
+		hc, err := NewHttpClient(context.Background(), srv.URL, log.DefaultLogger,
+			WithHttpRetry(RetryPolicy{MaxAttempts: 3, MaxBackoff: time.Millisecond, RetryableStatus: []int{http.StatusServiceUnavailable}, MaxBufferSize: test.size}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		attempts = 0
+		req, _ = http.NewRequest(http.MethodPut, srv.URL, io.MultiReader(strings.NewReader("payload")))
+		_, _ = hc.Do(req)
+		if attempts != test.attempts {
+			t.Fatalf("expected %d attempts with a buffer size of %d, got %d", test.attempts, test.size, attempts)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryOpenBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var attempts int
+	breaker := &breakerTransport{next: http.DefaultTransport, group: newBreakerGroup([]BreakerOption{WithBreakerThreshold(1, 1)})}
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	rt := &retryTransport{policy: policy, next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return breaker.RoundTrip(req)
+	})}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if res, err := breaker.RoundTrip(req); err == nil {
+		res.Body.Close()
+	}
+	if _, err := rt.RoundTrip(req); errors.Reason(err) != ReasonCircuitOpen || attempts != 1 {
+		t.Fatalf("expected the open breaker to fail fast, got %v after %d attempts", err, attempts)
+	}
+}
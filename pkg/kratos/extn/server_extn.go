@@ -0,0 +1,183 @@
+package extn
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/recovery"
+	"github.com/go-kratos/kratos/v2/middleware/selector"
+	"github.com/go-kratos/kratos/v2/middleware/tracing"
+	kgrpc "github.com/go-kratos/kratos/v2/transport/grpc"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+// ServerOption is a NewHttpServer and NewGrpcServer option.
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	address           string
+	timeout           time.Duration
+	tlsConf           *tls.Config
+	skipAuth          []string
+	skipLogging       []string
+	auditSink         AuditSink
+	idempotencyStore  IdempotencyStore
+	idempotencyOpts   []IdempotencyOption
+	correlationIdOpts []CorrelationIdOption
+	identityOpts      []IdentityOption
+	middleware        []middleware.Middleware
+}
+
+// WithServerAddress sets the listen address, e.g. ":8000".
+func WithServerAddress(address string) ServerOption {
+	return func(o *serverOptions) {
+		o.address = address
+	}
+}
+
+// WithServerTimeout sets the request timeout.
+func WithServerTimeout(timeout time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithServerTLSConfig enables TLS.
+func WithServerTLSConfig(conf *tls.Config) ServerOption {
+	return func(o *serverOptions) {
+		o.tlsConf = conf
+	}
+}
+
+// WithSkipAuth disables the security header validation for the given operations,
+// entries ending with "/" match every operation with that prefix.
+func WithSkipAuth(operations ...string) ServerOption {
+	return func(o *serverOptions) {
+		o.skipAuth = append(o.skipAuth, operations...)
+	}
+}
+
+// WithSkipLogging disables the logging middleware for the given operations,
+// entries ending with "/" match every operation with that prefix.
+func WithSkipLogging(operations ...string) ServerOption {
+	return func(o *serverOptions) {
+		o.skipLogging = append(o.skipLogging, operations...)
+	}
+}
+
+// WithServerAudit enables the Audit middleware writing to sink.
+func WithServerAudit(sink AuditSink) ServerOption {
+	return func(o *serverOptions) {
+		o.auditSink = sink
+	}
+}
+
+// WithServerIdempotency enables the Idempotency middleware backed by store.
+func WithServerIdempotency(store IdempotencyStore, opts ...IdempotencyOption) ServerOption {
+	return func(o *serverOptions) {
+		o.idempotencyStore = store
+		o.idempotencyOpts = opts
+	}
+}
+
+// WithServerCorrelationId sets the ServerCorrelationIdInjector options.
+func WithServerCorrelationId(opts ...CorrelationIdOption) ServerOption {
+	return func(o *serverOptions) {
+		o.correlationIdOpts = opts
+	}
+}
+
+// WithServerIdentity sets the ServerIdentityInjector options.
+func WithServerIdentity(opts ...IdentityOption) ServerOption {
+	return func(o *serverOptions) {
+		o.identityOpts = opts
+	}
+}
+
+// WithServerMiddleware appends middleware after the standard stack.
+func WithServerMiddleware(m ...middleware.Middleware) ServerOption {
+	return func(o *serverOptions) {
+		o.middleware = append(o.middleware, m...)
+	}
+}
+
+func newServerOptions(opts []ServerOption) *serverOptions {
+	o := &serverOptions{timeout: time.Second * 10}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// unless applies m to every operation not matching operations
+func unless(operations []string, m middleware.Middleware) middleware.Middleware {
+	if len(operations) == 0 {
+		return m
+	}
+	return selector.Server(m).Match(func(_ context.Context, operation string) bool {
+		for _, op := range operations {
+			if op == operation || (strings.HasSuffix(op, "/") && strings.HasPrefix(operation, op)) {
+				return false
+			}
+		}
+		return true
+	}).Build()
+}
+
+// serverMiddleware returns the standard server stack, in order:
+// recovery, tracing, correlation id, caller identity, logging, audit, security header
+// validation, idempotency and finally the extra middleware. Logging and audit run before the
+// security validation so that rejected requests are logged and audited.
+func serverMiddleware(logger log.Logger, o *serverOptions) []middleware.Middleware {
+	ms := []middleware.Middleware{
+		recovery.Recovery(),
+		tracing.Server(tracing.WithPropagator(defaultPropagator)),
+		ServerCorrelationIdInjector(o.correlationIdOpts...),
+		ServerIdentityInjector(o.identityOpts...),
+		unless(o.skipLogging, Server(logger)),
+	}
+	if o.auditSink != nil {
+		ms = append(ms, Audit(o.auditSink))
+	}
+	ms = append(ms, unless(o.skipAuth, ServerSecurityHeaderValidator()))
+	if o.idempotencyStore != nil {
+		ms = append(ms, Idempotency(o.idempotencyStore, o.idempotencyOpts...))
+	}
+	return append(ms, o.middleware...)
+}
+
+// NewHttpServer returns an HTTP server with the standard server middleware stack.
+func NewHttpServer(logger log.Logger, opts ...ServerOption) *khttp.Server {
+	o := newServerOptions(opts)
+	serverOpts := []khttp.ServerOption{
+		khttp.Middleware(serverMiddleware(logger, o)...),
+		khttp.Timeout(o.timeout),
+	}
+	if o.address != "" {
+		serverOpts = append(serverOpts, khttp.Address(o.address))
+	}
+	if o.tlsConf != nil {
+		serverOpts = append(serverOpts, khttp.TLSConfig(o.tlsConf))
+	}
+	return khttp.NewServer(serverOpts...)
+}
+
+// NewGrpcServer returns a gRPC server with the standard server middleware stack.
+func NewGrpcServer(logger log.Logger, opts ...ServerOption) *kgrpc.Server {
+	o := newServerOptions(opts)
+	serverOpts := []kgrpc.ServerOption{
+		kgrpc.Middleware(serverMiddleware(logger, o)...),
+		kgrpc.Timeout(o.timeout),
+	}
+	if o.address != "" {
+		serverOpts = append(serverOpts, kgrpc.Address(o.address))
+	}
+	if o.tlsConf != nil {
+		serverOpts = append(serverOpts, kgrpc.TLSConfig(o.tlsConf))
+	}
+	return kgrpc.NewServer(serverOpts...)
+}
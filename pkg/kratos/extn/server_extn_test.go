@@ -0,0 +1,51 @@
+package extn
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestServerMiddlewareSkip(t *testing.T) {
+	var buf bytes.Buffer
+	o := newServerOptions([]ServerOption{WithSkipAuth("/health.Health/"), WithSkipLogging("/health.Health/Check")})
+	handler := middleware.Chain(serverMiddleware(log.NewStdLogger(&buf), o)...)(
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+
+	if _, err := handler(transport.NewServerContext(context.Background(), newTestTransport("/health.Health/Check")), nil); err != nil {
+		t.Fatalf("expected auth to be skipped, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected logging to be skipped, got %q", buf.String())
+	}
+
+	_, err := handler(transport.NewServerContext(context.Background(), newTestTransport("/api.Accounts/Get")), nil)
+	if !errors.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("expected rejected request to be logged")
+	}
+}
+
+func TestServerMiddlewareAuditsRejections(t *testing.T) {
+	sink := NewMemoryAuditSink()
+	o := newServerOptions([]ServerOption{WithServerAudit(sink)})
+	handler := middleware.Chain(serverMiddleware(log.NewStdLogger(&bytes.Buffer{}), o)...)(
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+
+	_, err := handler(transport.NewServerContext(context.Background(), newTestTransport("/options.AuditTestService/Update")), nil)
+	events := sink.Events()
+	if !errors.IsUnauthorized(err) || len(events) != 1 || events[0].Reason != "UNAUTHORIZED" {
+		t.Fatalf("expected the rejected request to be audited, got %v and %+v", err, events)
+	}
+}
@@ -0,0 +1,177 @@
+package extn
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// LevelFatal is the slog level matching log.LevelFatal.
+const LevelFatal = slog.Level(12)
+
+type redactedValue struct {
+	v interface{}
+}
+
+// Redacted returns a slog.LogValuer resolving v to its log representation, applying
+// the Sensitive annotations of proto messages and the Redacter interface.
+func Redacted(v interface{}) slog.LogValuer {
+	return redactedValue{v: v}
+}
+
+func (r redactedValue) LogValue() slog.Value {
+	return slog.StringValue(extractArgs(r.v))
+}
+
+// redactAttr resolves attr and replaces proto messages and Redacters with their redacted form.
+func redactAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	switch attr.Value.Kind() {
+	case slog.KindAny:
+		switch attr.Value.Any().(type) {
+		case proto.Message, Redacter:
+			attr.Value = slog.StringValue(extractArgs(attr.Value.Any()))
+		}
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		attrs := make([]slog.Attr, 0, len(group))
+		for _, a := range group {
+			attrs = append(attrs, redactAttr(a))
+		}
+		attr.Value = slog.GroupValue(attrs...)
+	}
+	return attr
+}
+
+type slogHandler struct {
+	logger log.Logger
+	level  slog.Leveler
+	attrs  []interface{}
+	group  string
+}
+
+// NewSlogHandler returns a slog.Handler writing records to a kratos logger. Records below
+// level are discarded, a nil level enables every record.
+func NewSlogHandler(logger log.Logger, level slog.Leveler) slog.Handler {
+	if level == nil {
+		level = slog.LevelDebug
+	}
+	return &slogHandler{logger: logger, level: level}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	keyvals := make([]interface{}, 0, 2+len(h.attrs)+2*r.NumAttrs())
+	keyvals = append(keyvals, log.DefaultMessageKey, r.Message)
+	keyvals = append(keyvals, h.attrs...)
+	r.Attrs(func(attr slog.Attr) bool {
+		keyvals = appendAttr(keyvals, h.group, attr)
+		return true
+	})
+	return log.WithContext(ctx, h.logger).Log(fromSlogLevel(r.Level), keyvals...)
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]interface{}(nil), h.attrs...)
+	for _, attr := range attrs {
+		clone.attrs = appendAttr(clone.attrs, h.group, attr)
+	}
+	return &clone
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// appendAttr flattens attr into keyvals, groups are expanded to dotted keys
+func appendAttr(keyvals []interface{}, prefix string, attr slog.Attr) []interface{} {
+	attr = redactAttr(attr)
+	if attr.Equal(slog.Attr{}) {
+		return keyvals
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix = prefix + attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			keyvals = appendAttr(keyvals, prefix, a)
+		}
+		return keyvals
+	}
+	return append(keyvals, prefix+attr.Key, attr.Value.Any())
+}
+
+type slogLogger struct {
+	handler slog.Handler
+}
+
+// NewSlogLogger returns a kratos logger writing records to a slog.Handler. The value of
+// log.DefaultMessageKey becomes the record message.
+func NewSlogLogger(handler slog.Handler) log.Logger {
+	return &slogLogger{handler: handler}
+}
+
+func (l *slogLogger) Log(level log.Level, keyvals ...interface{}) error {
+	ctx := context.Background()
+	slevel := toSlogLevel(level)
+	if !l.handler.Enabled(ctx, slevel) {
+		return nil
+	}
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "KEYVALS UNPAIRED")
+	}
+	var msg string
+	attrs := make([]slog.Attr, 0, len(keyvals)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		if key == log.DefaultMessageKey && msg == "" {
+			msg = fmt.Sprint(keyvals[i+1])
+			continue
+		}
+		attrs = append(attrs, redactAttr(slog.Any(key, keyvals[i+1])))
+	}
+	r := slog.NewRecord(time.Now(), slevel, msg, 0)
+	r.AddAttrs(attrs...)
+	return l.handler.Handle(ctx, r)
+}
+
+func fromSlogLevel(level slog.Level) log.Level {
+	switch {
+	case level >= LevelFatal:
+		return log.LevelFatal
+	case level >= slog.LevelError:
+		return log.LevelError
+	case level >= slog.LevelWarn:
+		return log.LevelWarn
+	case level >= slog.LevelInfo:
+		return log.LevelInfo
+	}
+	return log.LevelDebug
+}
+
+func toSlogLevel(level log.Level) slog.Level {
+	switch level {
+	case log.LevelDebug:
+		return slog.LevelDebug
+	case log.LevelWarn:
+		return slog.LevelWarn
+	case log.LevelError:
+		return slog.LevelError
+	case log.LevelFatal:
+		return LevelFatal
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,32 @@
+package extn
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	pb "github.com/achuala/kratos-extn/api/gen"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewSlogHandler(log.NewStdLogger(&buf), slog.LevelInfo))
+	logger.Debug("dropped")
+	logger.WithGroup("req").Info("hello", "data", &pb.SensitiveTestData{Name: "Name to be Masked", Secret: "Secret"})
+	out := buf.String()
+	if strings.Contains(out, "dropped") || strings.Contains(out, "Secret") || !strings.Contains(out, "req.data=") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.NewTextHandler(&buf, nil))
+	_ = logger.Log(log.LevelWarn, "msg", "hello", "data", &pb.SensitiveTestData{Secret: "Secret"}, "other", Redacted(&pb.SensitiveTestData{Secret: "Secret"}))
+	out := buf.String()
+	if strings.Contains(out, "Secret") || !strings.Contains(out, "level=WARN msg=hello") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
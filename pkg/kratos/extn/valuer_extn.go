@@ -0,0 +1,60 @@
+package extn
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// CorrelationID returns a log.Valuer resolving the correlation id of the request,
+// e.g. log.With(logger, "correlationId", extn.CorrelationID()).
+func CorrelationID() log.Valuer {
+	return ctxValuer(CtxCorrelationIdKey)
+}
+
+// SystemPeer returns a log.Valuer resolving the calling system of the request, from the
+// RequestIdentity of the context or else the DefaultIdentityHeaders of the request.
+func SystemPeer() log.Valuer {
+	return identityValuer(SystemPeerFromContext, DefaultIdentityHeaders.SystemPeer)
+}
+
+// Channel returns a log.Valuer resolving the channel of the request, like SystemPeer.
+func Channel() log.Valuer {
+	return identityValuer(ChannelFromContext, DefaultIdentityHeaders.Channel)
+}
+
+// UserID returns a log.Valuer resolving the user id of the request, like SystemPeer.
+func UserID() log.Valuer {
+	return identityValuer(UserIdFromContext, DefaultIdentityHeaders.UserId)
+}
+
+// identityValuer resolves a RequestIdentity field, or the header carrying it when the
+// ServerIdentityInjector did not run
+func identityValuer(accessor func(context.Context) string, header string) log.Valuer {
+	return func(ctx context.Context) interface{} {
+		if ctx == nil {
+			return ""
+		}
+		if _, ok := IdentityFromContext(ctx); ok {
+			return accessor(ctx)
+		}
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			return tr.RequestHeader().Get(header)
+		}
+		return ""
+	}
+}
+
+// ctxValuer resolves the string stored under key, or an empty string
+func ctxValuer(key CtxKey) log.Valuer {
+	return func(ctx context.Context) interface{} {
+		if ctx == nil {
+			return ""
+		}
+		if v, ok := ctx.Value(key).(string); ok {
+			return v
+		}
+		return ""
+	}
+}
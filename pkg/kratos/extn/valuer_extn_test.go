@@ -0,0 +1,43 @@
+package extn
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestCorrelationIDValuer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.With(log.NewStdLogger(&buf), "correlationId", CorrelationID())
+	ctx := context.WithValue(context.Background(), CtxCorrelationIdKey, "corr-1")
+	_ = log.WithContext(ctx, logger).Log(log.LevelInfo, "msg", "hello")
+	if !strings.Contains(buf.String(), "correlationId=corr-1") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestIdentityValuers(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.With(log.NewStdLogger(&buf), "systemPeer", SystemPeer(), "userId", UserID())
+	tr := newTestTransport("/api.Accounts/Get")
+	tr.reqHeader.Set(string(CtxSystemPeerKey), "billing")
+	tr.reqHeader.Set(string(CtxUserIdKey), "u-1")
+
+	// without the ServerIdentityInjector the request headers are used
+	ctx := transport.NewServerContext(context.Background(), tr)
+	_ = log.WithContext(ctx, logger).Log(log.LevelInfo, "msg", "hello")
+	if !strings.Contains(buf.String(), "systemPeer=billing userId=u-1") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	ctx = NewIdentityContext(ctx, &RequestIdentity{SystemPeer: "payments", UserId: "u-2"})
+	_ = log.WithContext(ctx, logger).Log(log.LevelInfo, "msg", "hello")
+	if !strings.Contains(buf.String(), "systemPeer=payments userId=u-2") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}